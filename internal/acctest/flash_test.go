@@ -0,0 +1,134 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package acctest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/client"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func TestAccNodeFlashResource_cacheBMC(t *testing.T) {
+	testAccNodeFlashResource(t, client.CacheLocationBMC)
+}
+
+func TestAccNodeFlashResource_cacheLocal(t *testing.T) {
+	testAccNodeFlashResource(t, client.CacheLocationLocal)
+}
+
+func testAccNodeFlashResource(t *testing.T, cache string) {
+	// Flashing wipes the node, so never do it to a physical board by accident
+	if sim == nil {
+		t.Skip("node_flash acceptance tests only run against the simulated BMC")
+	}
+
+	// Keep the local image cache out of the real home directory
+	t.Setenv("HOME", t.TempDir())
+
+	imagePath := writeTestImage(t, []byte("turingpi "+cache))
+	sha256, err := client.CalculateFileSHA256(imagePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { PreCheck(t) },
+		ProtoV6ProviderFactories: ProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig() + testAccNodeFlashResourceConfig(imagePath, cache),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("turingpi_node_flash.test", "cache", cache),
+					resource.TestCheckResourceAttr("turingpi_node_flash.test", "sha256", sha256),
+					resource.TestCheckResourceAttr("turingpi_node_flash.test", "flash_status", "success"),
+					testAccCheckNodeFlashed(2, sha256, cache == client.CacheLocationBMC),
+				),
+			},
+		},
+	})
+}
+
+func TestAccNodeFlashResource_imageURLCacheBMC(t *testing.T) {
+	if sim == nil {
+		t.Skip("node_flash acceptance tests only run against the simulated BMC")
+	}
+
+	t.Setenv("HOME", t.TempDir())
+
+	image := []byte("turingpi image_url")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(image)
+	}))
+	defer server.Close()
+	sha256, err := client.CalculateFileSHA256(writeTestImage(t, image))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { PreCheck(t) },
+		ProtoV6ProviderFactories: ProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig() + fmt.Sprintf(`
+resource "turingpi_node_flash" "test" {
+  node      = 3
+  image_url = %q
+  cache     = "bmc"
+}
+`, server.URL+"/image.img"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("turingpi_node_flash.test", "sha256", sha256),
+					resource.TestCheckResourceAttr("turingpi_node_flash.test", "flash_status", "success"),
+					testAccCheckNodeFlashed(3, sha256, true),
+				),
+			},
+		},
+	})
+}
+
+// writeTestImage writes data to a temporary image file and returns its path.
+func writeTestImage(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "image.img")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testAccCheckNodeFlashed verifies that the simulated BMC wrote the image to
+// node, from its own filesystem when onBMC is set.
+func testAccCheckNodeFlashed(node int, sha256 string, onBMC bool) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		flash, ok := sim.Flashed(node)
+		if !ok {
+			return fmt.Errorf("node %d was not flashed", node)
+		}
+		if flash.SHA256 != sha256 {
+			return fmt.Errorf("node %d was flashed with %s, want %s", node, flash.SHA256, sha256)
+		}
+		if flash.OnBMC != onBMC {
+			return fmt.Errorf("node %d flashed from the BMC filesystem = %t, want %t", node, flash.OnBMC, onBMC)
+		}
+		return nil
+	}
+}
+
+func testAccNodeFlashResourceConfig(imagePath, cache string) string {
+	return fmt.Sprintf(`
+resource "turingpi_node_flash" "test" {
+  node       = 2
+  image_path = %q
+  cache      = %q
+}
+`, filepath.ToSlash(imagePath), cache)
+}
@@ -0,0 +1,63 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package acctest
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+func TestAccFunctions(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "image.img")
+	if err := os.WriteFile(imagePath, []byte("turingpi"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: ProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+output "sha256" {
+  value = provider::turingpi::file_sha256("` + filepath.ToSlash(imagePath) + `")
+}
+
+output "compression" {
+  value = provider::turingpi::detect_compression("https://example.com/ubuntu.img.xz?download=1")
+}
+
+output "uncompressed" {
+  value = provider::turingpi::detect_compression("https://example.com/ubuntu.img")
+}
+
+output "power_id" {
+  value = provider::turingpi::node_id("power", 2)
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckOutput("sha256", "d5a301457e031f43e52722c9440f89a94d59eee373a569919e30e0dbaee419b5"),
+					resource.TestCheckOutput("compression", "xz"),
+					resource.TestCheckOutput("uncompressed", ""),
+					resource.TestCheckOutput("power_id", "node-2-power"),
+				),
+			},
+			{
+				Config: `
+output "bad_node" {
+  value = provider::turingpi::node_id("usb", 5)
+}
+`,
+				ExpectError: regexp.MustCompile(`value must be between 1 and 4`),
+			},
+		},
+	})
+}
@@ -0,0 +1,51 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package acctest
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/bmcsim"
+)
+
+// sim is the simulated BMC the acceptance tests run against when
+// TURINGPI_HOST is not set. It is nil when testing a physical board.
+var sim *bmcsim.Server
+
+func TestMain(m *testing.M) {
+	if os.Getenv("TF_ACC") != "" && os.Getenv("TURINGPI_HOST") == "" {
+		var err error
+		sim, err = bmcsim.Start(bmcsim.Config{})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to start simulated BMC: %s\n", err)
+			os.Exit(1)
+		}
+		for key, value := range sim.Env() {
+			os.Setenv(key, value)
+		}
+	}
+
+	code := m.Run()
+	if sim != nil {
+		sim.Close()
+	}
+	os.Exit(code)
+}
+
+// providerConfig returns the provider block for test configurations. The
+// simulator listens on a random SSH port with its own host key, which the
+// environment variables cannot express.
+func providerConfig() string {
+	if sim == nil {
+		return ""
+	}
+	return fmt.Sprintf(`
+provider "turingpi" {
+  ssh_port                 = %d
+  ssh_host_key_fingerprint = %q
+}
+`, sim.SSHPort, sim.HostKeyFingerprint)
+}
@@ -4,9 +4,11 @@
 package acctest
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
 )
 
 func TestAccNodePowerResource(t *testing.T) {
@@ -20,6 +22,7 @@ func TestAccNodePowerResource(t *testing.T) {
 				Check: resource.ComposeAggregateTestCheckFunc(
 					resource.TestCheckResourceAttr("turingpi_node_power.test", "node", "1"),
 					resource.TestCheckResourceAttr("turingpi_node_power.test", "power_on", "true"),
+					testAccCheckNodePower(1, true),
 				),
 			},
 			// Update testing
@@ -27,6 +30,7 @@ func TestAccNodePowerResource(t *testing.T) {
 				Config: testAccNodePowerResourceConfig(false),
 				Check: resource.ComposeAggregateTestCheckFunc(
 					resource.TestCheckResourceAttr("turingpi_node_power.test", "power_on", "false"),
+					testAccCheckNodePower(1, false),
 				),
 			},
 			// ImportState testing
@@ -39,6 +43,20 @@ func TestAccNodePowerResource(t *testing.T) {
 	})
 }
 
+// testAccCheckNodePower verifies the power state the simulated BMC ended up
+// in. Physical boards are only checked through the resource attributes.
+func testAccCheckNodePower(node int, on bool) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		if sim == nil {
+			return nil
+		}
+		if got := sim.Power(node); got != on {
+			return fmt.Errorf("node %d power = %t, want %t", node, got, on)
+		}
+		return nil
+	}
+}
+
 func testAccNodePowerResourceConfig(powerOn bool) string {
 	powerOnStr := "false"
 	if powerOn {
@@ -0,0 +1,384 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package bmcsim
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// transfer is a flash announced with "set flash".
+type transfer struct {
+	handle int
+	node   int
+	file   string
+	length int64
+	sha256 string
+
+	done  bool
+	err   string
+	abort chan struct{} // Closed when the transfer is cancelled
+}
+
+// newTransfer registers a transfer as the most recent one.
+// The caller must hold s.mu.
+func (s *Server) newTransfer(node int, file string, length int64, sha256 string) *transfer {
+	t := &transfer{
+		handle: len(s.transfers) + 1,
+		node:   node,
+		file:   file,
+		length: length,
+		sha256: sha256,
+		abort:  make(chan struct{}),
+	}
+	s.transfers[t.handle] = t
+	s.last = t
+	return t
+}
+
+// finish waits FlashDuration, then records flash as written unless the
+// transfer was cancelled or ctx ended first.
+func (s *Server) finish(ctx context.Context, t *transfer, flash Flash) bool {
+	if s.flashDuration > 0 {
+		timer := time.NewTimer(s.flashDuration)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-t.abort:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.err != "" {
+		return false
+	}
+	t.done = true
+	s.flashed[t.node] = flash
+	return true
+}
+
+func (s *Server) handleAuthenticate(w http.ResponseWriter, r *http.Request) {
+	var creds struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		http.Error(w, "malformed request", http.StatusBadRequest)
+		return
+	}
+	if creds.Username != s.username || creds.Password != s.password {
+		http.Error(w, "invalid credentials", http.StatusForbidden)
+		return
+	}
+
+	token := rand.Text()
+	s.mu.Lock()
+	s.tokens[token] = true
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]string{"id": token})
+}
+
+// authorized rejects requests without a bearer token issued by
+// handleAuthenticate.
+func (s *Server) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		s.mu.Lock()
+		ok := s.tokens[token]
+		s.mu.Unlock()
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleLegacy serves "/api/bmc?opt=get|set&type=...".
+func (s *Server) handleLegacy(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	op := query.Get("opt") + " " + query.Get("type")
+
+	var result interface{}
+	var err error
+	switch op {
+	case "get power":
+		result = s.getPower()
+	case "set power":
+		err = s.setPower(query)
+	case "get usb":
+		result = s.getUSB()
+	case "set usb":
+		err = s.setUSB(query)
+	case "get other":
+		result = []map[string]string{{
+			"api":       "1.1",
+			"buildtime": "2024-01-01 00:00:00",
+			"ip":        "127.0.0.1",
+			"mac":       "02:00:00:00:00:01",
+		}}
+	case "get about":
+		result = []map[string]string{{
+			"api":           "1.1",
+			"version":       "2.0.5",
+			"build_version": "2024.01",
+			"buildroot":     "Buildroot 2024.01",
+		}}
+	case "set flash":
+		s.startFlash(w, query)
+		return
+	case "get flash":
+		writeJSON(w, s.flashStatus())
+		return
+	case "set update":
+		err = s.flashFromBMC(r.Context(), query)
+	case "set cancel":
+		s.cancelFlash()
+	default:
+		http.Error(w, fmt.Sprintf("unsupported request %q", op), http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		writeJSON(w, map[string]string{"error": err.Error()})
+		return
+	}
+	if result == nil {
+		result = "ok"
+	}
+	writeJSON(w, map[string]interface{}{
+		"response": []map[string]interface{}{{"result": result}},
+	})
+}
+
+func (s *Server) getPower() interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := map[string]int{}
+	for node, on := range s.power {
+		status[fmt.Sprintf("node%d", node)] = 0
+		if on {
+			status[fmt.Sprintf("node%d", node)] = 1
+		}
+	}
+	return []map[string]int{status}
+}
+
+func (s *Server) setPower(query url.Values) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for node := 1; node <= 4; node++ {
+		switch query.Get(fmt.Sprintf("node%d", node)) {
+		case "":
+		case "0":
+			s.power[node] = false
+		case "1":
+			s.power[node] = true
+		default:
+			return fmt.Errorf("invalid power state for node%d", node)
+		}
+	}
+	return nil
+}
+
+func (s *Server) getUSB() interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	route := "USB-A"
+	if s.usb.BMC {
+		route = "BMC"
+	}
+	return []map[string]string{{
+		"node":  fmt.Sprintf("Node %d", s.usb.Node),
+		"mode":  s.usb.Mode,
+		"route": route,
+	}}
+}
+
+func (s *Server) setUSB(query url.Values) error {
+	node, err := parseNode(query.Get("node"))
+	if err != nil {
+		return err
+	}
+	mode, err := strconv.Atoi(query.Get("mode"))
+	if err != nil {
+		return fmt.Errorf("invalid mode %q", query.Get("mode"))
+	}
+
+	// Bit 2 selects the BMC route; the low bits select the mode
+	var name string
+	switch mode &^ (1 << 2) {
+	case 0:
+		name = "Host"
+	case 1:
+		name = "Device"
+	case 2:
+		name = "Flash"
+	default:
+		return fmt.Errorf("invalid mode %d", mode)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usb = USB{Node: node, Mode: name, BMC: mode&(1<<2) != 0}
+	return nil
+}
+
+// startFlash announces an upload and answers with its handle.
+func (s *Server) startFlash(w http.ResponseWriter, query url.Values) {
+	node, err := parseNode(query.Get("node"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	length, err := strconv.ParseInt(query.Get("length"), 10, 64)
+	if err != nil || length < 0 {
+		http.Error(w, "invalid length", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	t := s.newTransfer(node, query.Get("file"), length, query.Get("sha256"))
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]int{"handle": t.handle})
+}
+
+// handleUpload receives the image for a transfer announced with "set flash"
+// and writes it to the node.
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	handle, _ := strconv.Atoi(r.PathValue("handle"))
+	s.mu.Lock()
+	t, ok := s.transfers[handle]
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "unknown handle", http.StatusNotFound)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "missing file field", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		http.Error(w, "failed to read upload", http.StatusBadRequest)
+		return
+	}
+	digest := hex.EncodeToString(hash.Sum(nil))
+
+	s.mu.Lock()
+	switch {
+	case size != t.length:
+		t.err = fmt.Sprintf("received %d bytes, expected %d", size, t.length)
+	case t.sha256 != "" && !strings.EqualFold(t.sha256, digest):
+		t.err = fmt.Sprintf("sha256 mismatch: got %s, expected %s", digest, t.sha256)
+	}
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+
+	// The write continues after the upload request has returned
+	flash := Flash{Node: t.node, Size: size, SHA256: digest, Path: t.file}
+	go s.finish(context.Background(), t, flash)
+}
+
+// cancelFlash aborts the most recent transfer if it is still running.
+func (s *Server) cancelFlash() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.last
+	if t == nil || t.done || t.err != "" {
+		return
+	}
+	t.err = "Flashing cancelled by user"
+	close(t.abort)
+	s.cancelled++
+}
+
+// flashStatus reports the most recent transfer in bmcd's format.
+func (s *Server) flashStatus() interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case s.last == nil:
+		return map[string]interface{}{}
+	case s.last.err != "":
+		return map[string]string{"Error": s.last.err}
+	case s.last.done:
+		return map[string]interface{}{"Done": map[string]int64{"bytes": s.last.length}}
+	default:
+		return map[string]interface{}{"Transferring": map[string]interface{}{"id": s.last.handle, "size": s.last.length}}
+	}
+}
+
+// flashFromBMC writes an image from the BMC filesystem to a node and
+// answers once it is written.
+func (s *Server) flashFromBMC(ctx context.Context, query url.Values) error {
+	node, err := parseNode(query.Get("node"))
+	if err != nil {
+		return err
+	}
+	imagePath := query.Get("path")
+
+	file, err := os.Open(s.localPath(imagePath))
+	if err != nil {
+		return fmt.Errorf("cannot open %s: no such file", imagePath)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return fmt.Errorf("cannot read %s: %s", imagePath, err)
+	}
+
+	s.mu.Lock()
+	t := s.newTransfer(node, imagePath, size, "")
+	s.mu.Unlock()
+
+	flash := Flash{Node: node, Size: size, SHA256: hex.EncodeToString(hash.Sum(nil)), Path: imagePath, OnBMC: true}
+	if !s.finish(ctx, t, flash) {
+		return fmt.Errorf("flashing node %d was interrupted", node)
+	}
+	return nil
+}
+
+// parseNode converts the API's 0-based node index to a node number.
+func parseNode(value string) (int, error) {
+	index, err := strconv.Atoi(value)
+	if err != nil || index < 0 || index > 3 {
+		return 0, fmt.Errorf("invalid node %q", value)
+	}
+	return index + 1, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
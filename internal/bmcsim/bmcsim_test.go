@@ -0,0 +1,322 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package bmcsim_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/bmcsim"
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/client"
+	tpi "github.com/davidroman0O/tpi/client"
+)
+
+func startSim(t *testing.T, cfg bmcsim.Config) (*bmcsim.Server, *client.Client) {
+	t.Helper()
+
+	sim, err := bmcsim.Start(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(sim.Close)
+
+	c, err := client.NewClient(context.Background(), client.Config{
+		Host:                  sim.Host,
+		Username:              "root",
+		Password:              "turing",
+		SSHUser:               "root",
+		SSHPassword:           "turing",
+		SSHPort:               sim.SSHPort,
+		SSHHostKeyFingerprint: sim.HostKeyFingerprint,
+		TLS:                   client.TLSConfig{CACertPEM: sim.CACertPEM},
+		Retry:                 client.RetryPolicy{MaxAttempts: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return sim, c
+}
+
+func TestPowerAndUSB(t *testing.T) {
+	sim, c := startSim(t, bmcsim.Config{})
+
+	if err := c.PowerOn(2); err != nil {
+		t.Fatal(err)
+	}
+	if !sim.Power(2) {
+		t.Error("node 2 is off after PowerOn")
+	}
+	status, err := c.PowerStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status[2] || status[1] {
+		t.Errorf("PowerStatus = %v, want only node 2 on", status)
+	}
+
+	if err := c.UsbSetHost(3, true); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sim.USB(), (bmcsim.USB{Node: 3, Mode: "Host", BMC: true}); got != want {
+		t.Errorf("USB = %+v, want %+v", got, want)
+	}
+	usb, err := c.UsbGetStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usb.Node != "Node 3" || usb.Mode != "Host" || usb.Route != "BMC" {
+		t.Errorf("UsbGetStatus = %+v", usb)
+	}
+}
+
+func TestFlash(t *testing.T) {
+	sim, c := startSim(t, bmcsim.Config{})
+
+	image := filepath.Join(t.TempDir(), "image.img")
+	if err := os.WriteFile(image, []byte("turingpi"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := client.CalculateFileSHA256(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.FlashNode(1, &tpi.FlashOptions{ImagePath: image, SHA256: sum}); err != nil {
+		t.Fatalf("FlashNode: %v", err)
+	}
+	flash, ok := sim.Flashed(1)
+	if !ok || flash.SHA256 != sum || flash.Size != 8 || flash.OnBMC {
+		t.Errorf("node 1 flash = %+v, %v", flash, ok)
+	}
+
+	if _, err := c.Run(client.NewCommand("mkdir", "-p", "/tmp/tpi-cache")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UploadFile(image, "/tmp/tpi-cache/image.img"); err != nil {
+		t.Fatalf("UploadFile: %v", err)
+	}
+	files, err := c.ListDirectory("/tmp/tpi-cache")
+	if err != nil || len(files) != 1 || files[0].Name != "image.img" {
+		t.Fatalf("ListDirectory = %v, %v", files, err)
+	}
+
+	if err := c.FlashNodeLocal(4, "/tmp/tpi-cache/image.img"); err != nil {
+		t.Fatalf("FlashNodeLocal: %v", err)
+	}
+	flash, ok = sim.Flashed(4)
+	if !ok || !flash.OnBMC || flash.SHA256 != sum {
+		t.Errorf("node 4 flash = %+v, %v", flash, ok)
+	}
+
+	if err := c.FlashNodeLocal(3, "/tmp/missing.img"); err == nil {
+		t.Error("FlashNodeLocal of a missing file succeeded")
+	}
+}
+
+func TestFlashCancelled(t *testing.T) {
+	sim, c := startSim(t, bmcsim.Config{FlashDuration: time.Hour})
+
+	image := filepath.Join(t.TempDir(), "image.img")
+	if err := os.WriteFile(image, []byte("turingpi"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.WriteFile("/tmp/image.img", []byte("turingpi")); err != nil {
+		t.Fatal(err)
+	}
+
+	flashes := map[string]func(context.Context) error{
+		"FlashNodeContext": func(ctx context.Context) error {
+			return c.FlashNodeContext(ctx, 1, &tpi.FlashOptions{ImagePath: image})
+		},
+		"FlashNodeLocalContext": func(ctx context.Context) error {
+			return c.FlashNodeLocalContext(ctx, 2, "/tmp/image.img")
+		},
+	}
+	for name, flash := range flashes {
+		cancelled := sim.Cancellations()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+		err := flash(ctx)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: got %v, want context.DeadlineExceeded", name, err)
+		}
+		if got := sim.Cancellations(); got != cancelled+1 {
+			t.Errorf("%s: BMC saw %d cancellations, want %d", name, got, cancelled+1)
+		}
+	}
+
+	for node := 1; node <= 2; node++ {
+		if flash, ok := sim.Flashed(node); ok {
+			t.Errorf("node %d was flashed after cancellation: %+v", node, flash)
+		}
+	}
+}
+
+func TestUploadFileCancelled(t *testing.T) {
+	_, c := startSim(t, bmcsim.Config{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.UploadFileContext(ctx, "bmcsim_test.go", "/tmp/upload"); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if _, err := c.RunContext(ctx, client.NewCommand("true")); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestBMCCacheUploadInterrupted(t *testing.T) {
+	sim, c := startSim(t, bmcsim.Config{SFTPWriteDelay: 20 * time.Millisecond})
+	t.Setenv("HOME", t.TempDir())
+
+	data := bytes.Repeat([]byte("turingpi"), 512<<10) // 4 MiB, over a second of writes
+	image := filepath.Join(t.TempDir(), "image.img")
+	if err := os.WriteFile(image, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+
+	cache, err := client.NewImageCache(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	_, err = cache.CacheImage(ctx, image, digest, client.CacheLocationBMC)
+	cancel()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("CacheImage: got %v, want context.DeadlineExceeded", err)
+	}
+
+	cached, err := cache.GetCachedImagePath(context.Background(), digest, client.CacheLocationBMC)
+	if err != nil || cached != "" {
+		t.Fatalf("GetCachedImagePath after an interrupted upload = %q, %v; want no cache hit", cached, err)
+	}
+	if _, err := sim.ReadFile("/tmp/tpi-cache/" + digest + ".img"); !os.IsNotExist(err) {
+		t.Errorf("truncated image left in the BMC cache: %v", err)
+	}
+
+	// The next upload completes and replaces the leftover part file
+	remotePath, err := cache.CacheImage(context.Background(), image, digest, client.CacheLocationBMC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := sim.ReadFile(remotePath); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("cached image is %d bytes (%v), want %d", len(got), err, len(data))
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	sim, c := startSim(t, bmcsim.Config{Password: "changed"})
+
+	if _, err := c.PowerStatus(); !errors.Is(err, client.ErrAuthentication) {
+		t.Errorf("PowerStatus with a wrong password: got %v, want ErrAuthentication", err)
+	}
+	if _, err := c.ListDirectory("/tmp"); !errors.Is(err, client.ErrSSHAuthentication) {
+		t.Errorf("ListDirectory with a wrong password: got %v, want ErrSSHAuthentication", err)
+	}
+
+	sim, c = startSim(t, bmcsim.Config{})
+	image := filepath.Join(t.TempDir(), "image.img")
+	if err := os.WriteFile(image, []byte("turingpi"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := c.FlashNode(1, &tpi.FlashOptions{ImagePath: image, SHA256: strings.Repeat("0", 64)})
+	if !errors.Is(err, client.ErrChecksumMismatch) {
+		t.Errorf("FlashNode with a wrong SHA256: got %v, want ErrChecksumMismatch", err)
+	}
+
+	sim.Close()
+	if _, err := c.PowerStatus(); !errors.Is(err, client.ErrUnreachable) {
+		t.Errorf("PowerStatus after the BMC went away: got %v, want ErrUnreachable", err)
+	}
+	if _, err := c.ListDirectory("/tmp"); !errors.Is(err, client.ErrUnreachable) {
+		t.Errorf("ListDirectory after the BMC went away: got %v, want ErrUnreachable", err)
+	}
+}
+
+func TestSSHConnectionReused(t *testing.T) {
+	sim, c := startSim(t, bmcsim.Config{})
+
+	image := filepath.Join(t.TempDir(), "image.img")
+	if err := os.WriteFile(image, []byte("turingpi"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Run(client.NewCommand("mkdir", "-p", "/tmp/tpi-cache")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UploadFile(image, "/tmp/tpi-cache/image.img"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ListDirectory("/tmp/tpi-cache"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A cancelled operation closes its session, not the shared connection
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.RunContext(ctx, client.NewCommand("true")); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if _, err := c.Run(client.NewCommand("true")); err != nil {
+		t.Fatal(err)
+	}
+	if got := sim.Handshakes(); got != 1 {
+		t.Errorf("SSH handshakes = %d, want 1", got)
+	}
+
+	sim.DropSSH()
+	if _, err := c.ListDirectory("/tmp/tpi-cache"); err != nil {
+		t.Fatalf("ListDirectory after the connection dropped: %v", err)
+	}
+	if got := sim.Handshakes(); got != 2 {
+		t.Errorf("SSH handshakes = %d, want 2 after reconnecting", got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	sim, c := startSim(t, bmcsim.Config{})
+
+	// Arguments reach the BMC verbatim, shell metacharacters included
+	dir := "/tmp/it's a $(dir)"
+	if _, err := c.Run(client.NewCommand("mkdir", "-p", "--", dir)); err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.WriteFile(dir+"/file", []byte("contents")); err != nil {
+		t.Fatal(err)
+	}
+	result, err := c.Run(client.NewCommand("cat", dir+"/file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Stdout != "contents" || result.Stderr != "" || result.ExitCode != 0 {
+		t.Errorf("cat = %+v", result)
+	}
+
+	result, err = c.Run(client.NewCommand("cat", "/tmp/missing"))
+	var cmdErr *client.CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode != 1 || !strings.Contains(cmdErr.Stderr, "can't open") {
+		t.Fatalf("cat of a missing file: got %v, want a CommandError with status 1", err)
+	}
+	if result == nil || result.ExitCode != 1 {
+		t.Errorf("cat of a missing file: result = %+v", result)
+	}
+
+	if _, err := c.Run(client.NewCommand("rm", "-rf", "--", dir)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sim.ReadFile(dir + "/file"); err == nil {
+		t.Error("file still exists after rm -rf")
+	}
+}
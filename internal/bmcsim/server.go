@@ -0,0 +1,280 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+// Package bmcsim runs a stand-in Turing Pi 2 BMC on the loopback interface.
+//
+// It serves the subset of the bmcd HTTP API the provider uses (power, usb,
+// info, about and flashing) over HTTPS, and an SSH server with exec and SFTP
+// support backed by a temporary directory. Acceptance tests point the
+// provider at it instead of a physical board and inspect its state afterwards.
+package bmcsim
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Config configures a simulated BMC.
+type Config struct {
+	// Username and Password are accepted by both the API and SSH.
+	// They default to root/turing, like the real firmware.
+	Username string
+	Password string
+
+	// FlashDuration is how long writing an image to a node takes once it
+	// has been received. Zero completes flashes immediately.
+	FlashDuration time.Duration
+
+	// SFTPWriteDelay slows every SFTP write down, so an upload can be
+	// interrupted partway. Zero writes at full speed.
+	SFTPWriteDelay time.Duration
+}
+
+// Flash describes a completed flash.
+type Flash struct {
+	Node   int    // 1-based node number
+	Size   int64  // Bytes written to the node
+	SHA256 string // Hex digest of the bytes written
+	Path   string // BMC path for flashes from the BMC filesystem, else the uploaded file name
+	OnBMC  bool   // Flashed from the BMC filesystem rather than uploaded
+}
+
+// USB describes the USB configuration.
+type USB struct {
+	Node int    // 1-based node number
+	Mode string // "Host", "Device" or "Flash"
+	BMC  bool   // Routed through the BMC instead of the USB-A connector
+}
+
+// Server is a running simulated BMC. All methods are safe for concurrent use.
+type Server struct {
+	// Host is the host:port of the HTTPS API.
+	Host string
+	// SSHPort is the port of the SSH server, on the same address as Host.
+	SSHPort int
+	// HostKeyFingerprint is the SHA256 fingerprint of the SSH host key.
+	HostKeyFingerprint string
+	// CACertPEM is the self-signed certificate served by the API.
+	CACertPEM string
+
+	username      string
+	password      string
+	root          string
+	flashDuration time.Duration
+	writeDelay    time.Duration
+
+	http   *httptest.Server
+	ssh    net.Listener
+	config *ssh.ServerConfig
+	wg     sync.WaitGroup
+	conns  map[net.Conn]struct{}
+
+	mu         sync.Mutex
+	tokens     map[string]bool
+	power      map[int]bool
+	usb        USB
+	flashed    map[int]Flash
+	transfers  map[int]*transfer
+	last       *transfer
+	cancelled  int
+	handshakes int
+	commands   []string
+}
+
+// Start starts a simulated BMC with all nodes off and node 1 in USB device
+// mode. Call Close to stop it and remove its filesystem.
+func Start(cfg Config) (*Server, error) {
+	if cfg.Username == "" {
+		cfg.Username = "root"
+	}
+	if cfg.Password == "" {
+		cfg.Password = "turing"
+	}
+
+	root, err := os.MkdirTemp("", "bmcsim-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create BMC filesystem: %w", err)
+	}
+
+	s := &Server{
+		username:      cfg.Username,
+		password:      cfg.Password,
+		root:          root,
+		flashDuration: cfg.FlashDuration,
+		writeDelay:    cfg.SFTPWriteDelay,
+		tokens:        map[string]bool{},
+		power:         map[int]bool{1: false, 2: false, 3: false, 4: false},
+		usb:           USB{Node: 1, Mode: "Device"},
+		flashed:       map[int]Flash{},
+		transfers:     map[int]*transfer{},
+		conns:         map[net.Conn]struct{}{},
+	}
+
+	if err := s.startSSH(); err != nil {
+		os.RemoveAll(root)
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/bmc/authenticate", s.handleAuthenticate)
+	mux.HandleFunc("GET /api/bmc", s.authorized(s.handleLegacy))
+	mux.HandleFunc("POST /api/bmc/upload/{handle}", s.authorized(s.handleUpload))
+	s.http = httptest.NewTLSServer(mux)
+
+	s.Host = s.http.Listener.Addr().String()
+	s.CACertPEM = string(pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: s.http.Certificate().Raw,
+	}))
+
+	return s, nil
+}
+
+// startSSH generates a host key and starts accepting SSH connections.
+func (s *Server) startSSH() error {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate SSH host key: %w", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to create SSH host key: %w", err)
+	}
+
+	s.config = &ssh.ServerConfig{
+		PasswordCallback: func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if meta.User() == s.username && string(password) == s.password {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("invalid credentials for %q", meta.User())
+		},
+	}
+	s.config.AddHostKey(signer)
+	s.HostKeyFingerprint = ssh.FingerprintSHA256(signer.PublicKey())
+
+	s.ssh, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("failed to listen for SSH: %w", err)
+	}
+	s.SSHPort = s.ssh.Addr().(*net.TCPAddr).Port
+
+	s.wg.Add(1)
+	go s.acceptSSH()
+	return nil
+}
+
+// Close stops the servers and removes the BMC filesystem.
+func (s *Server) Close() {
+	s.http.Close()
+	s.ssh.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	os.RemoveAll(s.root)
+}
+
+// Env returns the TURINGPI_* environment variables that point the provider
+// at the simulator.
+func (s *Server) Env() map[string]string {
+	return map[string]string{
+		"TURINGPI_HOST":     s.Host,
+		"TURINGPI_USERNAME": s.username,
+		"TURINGPI_PASSWORD": s.password,
+	}
+}
+
+// SetPower sets the power state of a node.
+func (s *Server) SetPower(node int, on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.power[node] = on
+}
+
+// Power returns the power state of a node.
+func (s *Server) Power(node int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.power[node]
+}
+
+// USB returns the current USB configuration.
+func (s *Server) USB() USB {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usb
+}
+
+// Flashed returns the last completed flash of a node, if any.
+func (s *Server) Flashed(node int) (Flash, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flash, ok := s.flashed[node]
+	return flash, ok
+}
+
+// Cancellations returns how many in-progress flashes were cancelled through
+// the API.
+func (s *Server) Cancellations() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cancelled
+}
+
+// Handshakes returns how many SSH connections completed a handshake.
+func (s *Server) Handshakes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.handshakes
+}
+
+// DropSSH closes every open SSH connection, as a BMC reboot or an idle
+// timeout would.
+func (s *Server) DropSSH() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+// Commands returns the commands run over SSH, in order.
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// ReadFile returns the contents of a file on the BMC filesystem.
+func (s *Server) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(s.localPath(name))
+}
+
+// WriteFile creates or replaces a file on the BMC filesystem.
+func (s *Server) WriteFile(name string, data []byte) error {
+	p := s.localPath(name)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0644)
+}
+
+// localPath maps an absolute BMC path into the simulator's root directory.
+// ".." components cannot escape the root.
+func (s *Server) localPath(name string) string {
+	return filepath.Join(s.root, filepath.FromSlash(path.Clean("/"+name)))
+}
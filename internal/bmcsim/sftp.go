@@ -0,0 +1,131 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package bmcsim
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// sftpHandlers serves the BMC filesystem rooted at the simulator's
+// temporary directory.
+func (s *Server) sftpHandlers() sftp.Handlers {
+	fs := &rootFS{server: s}
+	return sftp.Handlers{FileGet: fs, FilePut: fs, FileCmd: fs, FileList: fs}
+}
+
+// rootFS implements the sftp request handlers on top of Server.localPath.
+type rootFS struct {
+	server *Server
+}
+
+func (fs *rootFS) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	return os.Open(fs.server.localPath(r.Filepath))
+}
+
+func (fs *rootFS) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	flags := os.O_WRONLY
+	pflags := r.Pflags()
+	if pflags.Creat {
+		flags |= os.O_CREATE
+	}
+	if pflags.Trunc {
+		flags |= os.O_TRUNC
+	}
+	if pflags.Excl {
+		flags |= os.O_EXCL
+	}
+	file, err := os.OpenFile(fs.server.localPath(r.Filepath), flags, 0644)
+	if err != nil || fs.server.writeDelay == 0 {
+		return file, err
+	}
+	return &slowWriter{file: file, delay: fs.server.writeDelay}, nil
+}
+
+// slowWriter delays every write to file.
+type slowWriter struct {
+	file  *os.File
+	delay time.Duration
+}
+
+func (w *slowWriter) WriteAt(p []byte, off int64) (int, error) {
+	time.Sleep(w.delay)
+	return w.file.WriteAt(p, off)
+}
+
+// Close lets the sftp server close the file at the end of the request.
+func (w *slowWriter) Close() error {
+	return w.file.Close()
+}
+
+func (fs *rootFS) Filecmd(r *sftp.Request) error {
+	p := fs.server.localPath(r.Filepath)
+	switch r.Method {
+	case "Setstat":
+		attrs, flags := r.Attributes(), r.AttrFlags()
+		if flags.Permissions {
+			if err := os.Chmod(p, attrs.FileMode().Perm()); err != nil {
+				return err
+			}
+		}
+		if flags.Size {
+			return os.Truncate(p, int64(attrs.Size))
+		}
+		return nil
+	case "Rename", "PosixRename":
+		return os.Rename(p, fs.server.localPath(r.Target))
+	case "Rmdir", "Remove":
+		return os.Remove(p)
+	case "Mkdir":
+		return os.Mkdir(p, 0755)
+	default:
+		return fmt.Errorf("%s is not supported", r.Method)
+	}
+}
+
+func (fs *rootFS) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	p := fs.server.localPath(r.Filepath)
+	switch r.Method {
+	case "List":
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, err
+		}
+		infos := make(listerAt, 0, len(entries))
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, info)
+		}
+		return infos, nil
+	case "Stat", "Lstat":
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt{info}, nil
+	default:
+		return nil, fmt.Errorf("%s is not supported", r.Method)
+	}
+}
+
+// listerAt serves a precomputed directory listing.
+type listerAt []os.FileInfo
+
+func (l listerAt) ListAt(dst []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(dst, l[offset:])
+	if n < len(dst) {
+		return n, io.EOF
+	}
+	return n, nil
+}
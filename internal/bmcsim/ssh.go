@@ -0,0 +1,209 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package bmcsim
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+func (s *Server) acceptSSH() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ssh.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serveSSH(conn)
+
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// serveSSH handles one SSH connection until the client disconnects.
+func (s *Server) serveSSH(conn net.Conn) {
+	defer conn.Close()
+
+	_, channels, requests, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.handshakes++
+	s.mu.Unlock()
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only session channels are supported")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go s.serveSession(channel, requests)
+	}
+}
+
+// serveSession handles the exec and sftp subsystem requests of a session.
+func (s *Server) serveSession(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+
+	for req := range requests {
+		switch req.Type {
+		case "exec":
+			var payload struct{ Command string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+			status := s.exec(payload.Command, channel, channel.Stderr())
+			channel.SendRequest("exit-status", false, binary.BigEndian.AppendUint32(nil, status))
+			return
+		case "subsystem":
+			var payload struct{ Name string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil || payload.Name != "sftp" {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+			server := sftp.NewRequestServer(channel, s.sftpHandlers())
+			server.Serve()
+			server.Close()
+			return
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// exec runs the few shell commands the provider issues against the BMC
+// filesystem and returns the exit status.
+func (s *Server) exec(command string, stdout, stderr io.Writer) uint32 {
+	s.mu.Lock()
+	s.commands = append(s.commands, command)
+	s.mu.Unlock()
+
+	args, err := splitCommand(command)
+	if err != nil {
+		fmt.Fprintf(stderr, "sh: %s\n", err)
+		return 2
+	}
+	if len(args) == 0 {
+		return 0
+	}
+
+	switch {
+	case args[0] == "true":
+		return 0
+	case len(args) > 1 && args[0] == "mkdir" && args[1] == "-p":
+		for _, dir := range operands(args[2:]) {
+			if err := os.MkdirAll(s.localPath(dir), 0755); err != nil {
+				fmt.Fprintf(stderr, "mkdir: can't create directory '%s'\n", dir)
+				return 1
+			}
+		}
+		return 0
+	case len(args) > 1 && args[0] == "rm" && args[1] == "-rf":
+		for _, target := range operands(args[2:]) {
+			os.RemoveAll(s.localPath(target))
+		}
+		return 0
+	case args[0] == "cat":
+		for _, name := range args[1:] {
+			data, err := s.ReadFile(name)
+			if err != nil {
+				fmt.Fprintf(stderr, "cat: can't open '%s': No such file or directory\n", name)
+				return 1
+			}
+			stdout.Write(data)
+		}
+		return 0
+	default:
+		fmt.Fprintf(stderr, "sh: %s: not found\n", args[0])
+		return 127
+	}
+}
+
+// operands drops a leading "--" that ends the options of a command.
+func operands(args []string) []string {
+	if len(args) > 0 && args[0] == "--" {
+		return args[1:]
+	}
+	return args
+}
+
+// splitCommand splits a command line into words, honouring single quotes,
+// double quotes and backslash escapes.
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			switch {
+			case r == '"':
+				quote = 0
+			case r == '\\' && i+1 < len(runes) && strings.ContainsRune(`"\$`+"`", runes[i+1]):
+				i++
+				word.WriteRune(runes[i])
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == '\\' && i+1 < len(runes):
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quoted string")
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,335 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiRequestTimeout bounds ordinary (non-upload) BMC API requests.
+const apiRequestTimeout = 10 * time.Second
+
+// StatusError is returned when the BMC API answers with a non-200 status.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// apiClient talks to the bmcd HTTP API over a transport the provider controls.
+type apiClient struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	userAgent  string
+	username   string
+	password   string
+
+	mu    sync.Mutex
+	token string
+}
+
+// newAPIClient creates an API client for host using scheme and tlsConfig.
+// A non-empty token is used as the bearer token until the BMC rejects it.
+func newAPIClient(host, scheme string, tlsConfig *tls.Config, username, password, token string) (*apiClient, error) {
+	baseURL, err := url.Parse(fmt.Sprintf("%s://%s", scheme, host))
+	if err != nil {
+		return nil, fmt.Errorf("invalid BMC address: %w", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &apiClient{
+		baseURL: baseURL,
+		// Deadlines are applied per request, since flash uploads can take an hour
+		httpClient: &http.Client{Transport: transport},
+		userAgent:  fmt.Sprintf("terraform-provider-turingpi (%s; %s)", runtime.GOOS, runtime.Version()),
+		username:   username,
+		password:   password,
+		token:      token,
+	}, nil
+}
+
+// endpoint returns the absolute URL for an API path and query.
+func (a *apiClient) endpoint(path string, query url.Values) string {
+	u := *a.baseURL
+	u.Path = path
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// hasCredentials reports whether a username or password is configured, i.e.
+// whether the client can log in rather than only use a preset token.
+func (a *apiClient) hasCredentials() bool {
+	return a.username != "" || a.password != ""
+}
+
+// login returns a bearer token, authenticating when none is cached or when
+// force is set.
+func (a *apiClient) login(ctx context.Context, force bool) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != "" && !force {
+		return a.token, nil
+	}
+	if !a.hasCredentials() {
+		return "", fmt.Errorf("%w: token rejected by BMC and no username or password configured", ErrAuthentication)
+	}
+
+	token, err := a.authenticate(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	a.token = token
+	return a.token, nil
+}
+
+// authenticate opens a new BMC session and returns its bearer token without
+// caching it.
+func (a *apiClient) authenticate(ctx context.Context) (string, error) {
+	body, err := json.Marshal(map[string]string{
+		"username": a.username,
+		"password": a.password,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal auth request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, apiRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint("/api/bmc/authenticate", nil), bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create auth request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", a.userAgent)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send auth request: %w", transportError(ctx, err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusUnauthorized {
+			return "", fmt.Errorf("%w: invalid credentials", ErrAuthentication)
+		}
+		return "", fmt.Errorf("authentication failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var result struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to parse auth response: %w", err)
+	}
+	if result.ID == "" {
+		return "", fmt.Errorf("invalid auth response: missing id field")
+	}
+
+	return result.ID, nil
+}
+
+// do sends req with the cached bearer token, if any. When the BMC answers 401
+// it authenticates and retries once; requests with a body are only retried
+// when they can be replayed through GetBody.
+func (a *apiClient) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("User-Agent", a.userAgent)
+
+	a.mu.Lock()
+	token := a.token
+	a.mu.Unlock()
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, transportError(req.Context(), err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	if req.Body != nil && req.GetBody == nil {
+		return nil, fmt.Errorf("request rejected as unauthorized")
+	}
+
+	token, err = a.login(req.Context(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, fmt.Errorf("failed to rewind request body: %w", err)
+		}
+	}
+	retry.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err = a.httpClient.Do(retry)
+	if err != nil {
+		return nil, transportError(req.Context(), err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: token rejected by BMC", ErrAuthentication)
+	}
+	return resp, nil
+}
+
+// call issues a legacy "/api/bmc?opt=...&type=..." request and returns the
+// response body of a successful call.
+func (a *apiClient) call(ctx context.Context, opt, typ string, params url.Values) ([]byte, error) {
+	query := url.Values{}
+	for k, v := range params {
+		query[k] = v
+	}
+	query.Set("opt", opt)
+	query.Set("type", typ)
+
+	ctx, cancel := context.WithTimeout(ctx, apiRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.endpoint("/api/bmc", query), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := a.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		statusErr := &StatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+		return nil, tagError(classifyBMCMessage(statusErr.Body), statusErr)
+	}
+
+	return body, nil
+}
+
+// transportError tags a failed HTTP exchange as ErrUnreachable, unless it
+// was cancelled or the BMC certificate was rejected.
+func transportError(ctx context.Context, err error) error {
+	if ctx.Err() != nil || isTLSError(err) {
+		return err
+	}
+	return tagError(ErrUnreachable, err)
+}
+
+// get reads a value from the BMC.
+func (a *apiClient) get(ctx context.Context, typ string, params url.Values) ([]byte, error) {
+	return a.call(ctx, "get", typ, params)
+}
+
+// set changes a value on the BMC and checks the response for an error field.
+func (a *apiClient) set(ctx context.Context, typ string, params url.Values) error {
+	body, err := a.call(ctx, "set", typ, params)
+	if err != nil {
+		return err
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		// Not every firmware returns JSON for set operations
+		return nil
+	}
+	if errMsg, ok := result["error"].(string); ok && errMsg != "" {
+		return tagError(classifyBMCMessage(errMsg), fmt.Errorf("server returned error: %s", errMsg))
+	}
+
+	return nil
+}
+
+// apiResult extracts the "result" payload from a bmcd response. Depending on
+// the firmware and endpoint this is either {"result": ...} or the legacy
+// {"response": [{"result": ...}]} envelope.
+func apiResult(body []byte) (json.RawMessage, error) {
+	var envelope struct {
+		Result   json.RawMessage `json:"result"`
+		Response []struct {
+			Result json.RawMessage `json:"result"`
+		} `json:"response"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if len(envelope.Result) > 0 {
+		return envelope.Result, nil
+	}
+	if len(envelope.Response) > 0 && len(envelope.Response[0].Result) > 0 {
+		return envelope.Response[0].Result, nil
+	}
+
+	return nil, fmt.Errorf("invalid response format")
+}
+
+// apiResultObject returns the result payload as an object. Endpoints that
+// wrap a single object in an array are unwrapped.
+func apiResultObject(body []byte) (map[string]interface{}, error) {
+	raw, err := apiResult(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(raw, &object); err == nil {
+		return object, nil
+	}
+
+	var array []map[string]interface{}
+	if err := json.Unmarshal(raw, &array); err != nil {
+		return nil, fmt.Errorf("unexpected result format: %s", string(raw))
+	}
+	if len(array) == 0 {
+		return nil, fmt.Errorf("empty result")
+	}
+
+	return array[0], nil
+}
+
+// apiResultStrings returns the string-valued fields of the result object.
+func apiResultStrings(body []byte) (map[string]string, error) {
+	object, err := apiResultObject(body)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(object))
+	for key, value := range object {
+		if s, ok := value.(string); ok {
+			values[key] = s
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,197 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// isTar reports whether head, the first block of a stream, is a tar header.
+func isTar(head []byte) bool {
+	return len(head) >= 263 && bytes.Equal(head[257:262], []byte("ustar"))
+}
+
+// memberChooser picks the image among the files of an archive, as they are
+// seen one by one. With a pattern, the largest file matching it is chosen.
+// Without one, the largest .img or .raw file is, or the only file of an
+// archive holding a single one.
+type memberChooser struct {
+	pattern string
+
+	names     []string // Every file seen
+	chosen    string
+	size      int64
+	candidate bool // Whether chosen is a .img or .raw file
+}
+
+// consider reports whether the file name of size bytes replaces the file
+// chosen so far.
+func (c *memberChooser) consider(name string, size int64) bool {
+	c.names = append(c.names, name)
+
+	candidate := isImageName(name)
+	if c.pattern != "" {
+		if !matchMember(c.pattern, name) {
+			return false
+		}
+		candidate = true
+	}
+
+	switch {
+	case c.chosen == "",
+		candidate && !c.candidate,
+		candidate == c.candidate && size > c.size:
+		c.chosen, c.size, c.candidate = name, size, candidate
+		return true
+	}
+	return false
+}
+
+// result returns the chosen file once the whole archive has been seen.
+func (c *memberChooser) result() (string, error) {
+	switch {
+	case len(c.names) == 0:
+		return "", fmt.Errorf("archive is empty")
+	case c.pattern != "" && c.chosen == "":
+		return "", fmt.Errorf("no archive member matches %q; the archive holds %s", c.pattern, c.members())
+	case !c.candidate && len(c.names) > 1:
+		return "", fmt.Errorf("archive holds no .img or .raw file; set archive_member to one of %s", c.members())
+	}
+	return c.chosen, nil
+}
+
+// members lists the files seen, for error messages.
+func (c *memberChooser) members() string {
+	const max = 20
+	names := c.names
+	more := ""
+	if len(names) > max {
+		names, more = names[:max], fmt.Sprintf(" and %d more", len(c.names)-max)
+	}
+	return strings.Join(names, ", ") + more
+}
+
+// matchMember reports whether the archive member name matches pattern, an
+// exact name or a path.Match glob. Patterns without a slash also match the
+// base name, so "*.img" finds an image in a subdirectory.
+func matchMember(pattern, name string) bool {
+	name = strings.TrimPrefix(name, "./")
+	if pattern == name {
+		return true
+	}
+	if ok, _ := path.Match(pattern, name); ok {
+		return true
+	}
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return false
+}
+
+func isImageName(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".img" || ext == ".raw"
+}
+
+// extractTar writes the member of the tar stream r chosen for pattern to dst
+// and returns its name, SHA256 and size. Each file that beats the one chosen
+// so far overwrites dst, so the archive is read once.
+func extractTar(r io.Reader, dst, pattern string) (member, sum string, size int64, err error) {
+	reader := tar.NewReader(r)
+	chooser := memberChooser{pattern: pattern}
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", "", 0, fmt.Errorf("failed to read tar: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || !chooser.consider(header.Name, header.Size) {
+			continue
+		}
+		if sum, size, err = writeFile(dst, reader); err != nil {
+			return "", "", 0, fmt.Errorf("failed to extract %s from tar: %w", header.Name, err)
+		}
+	}
+
+	member, err = chooser.result()
+	if err != nil {
+		return "", "", 0, err
+	}
+	return member, sum, size, nil
+}
+
+// extractZip extracts the member of the zip archive src chosen for pattern to
+// dst and returns its SHA256.
+func extractZip(ctx context.Context, src, dst, pattern string) (_ string, err error) {
+	_, span := startSpan(ctx, "download.decompress", attrCompression.String("zip"))
+	defer func() { endSpan(span, err) }()
+
+	if stat, err := os.Stat(src); err == nil {
+		span.SetAttributes(attrBytes.Int64(stat.Size()))
+	}
+
+	reader, err := zip.OpenReader(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to open zip: %w", err)
+	}
+	defer reader.Close()
+
+	chooser := memberChooser{pattern: pattern}
+	var file *zip.File
+	for _, f := range reader.File {
+		if f.Mode().IsRegular() && chooser.consider(f.Name, int64(f.UncompressedSize64)) {
+			file = f
+		}
+	}
+	if _, err := chooser.result(); err != nil {
+		return "", err
+	}
+	span.SetAttributes(attrArchiveMember.String(file.Name))
+
+	srcFile, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open file in zip: %w", err)
+	}
+	defer srcFile.Close()
+
+	sum, written, err := writeFile(dst, srcFile)
+	span.SetAttributes(attrBytesOut.Int64(written))
+	if err != nil {
+		return "", fmt.Errorf("failed to extract from zip: %w", err)
+	}
+	return sum, nil
+}
+
+// writeFile writes r to a new file at path and returns its SHA256 and size.
+// The file is removed if writing fails.
+func writeFile(path string, r io.Reader) (string, int64, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return "", 0, err
+	}
+
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(file, hash), r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", written, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), written, nil
+}
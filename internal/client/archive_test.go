@@ -0,0 +1,199 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ulikunitz/xz"
+)
+
+type archiveFile struct {
+	name string
+	data []byte
+}
+
+// bundleFiles mimics a vendor bundle: the image among smaller files, not
+// first.
+var bundleFiles = []archiveFile{
+	{"README.md", []byte("Flash images/server.img to your node.\n")},
+	{"images/boot.raw", bytes.Repeat([]byte{0}, 1024)},
+	{"images/server.img", testImage},
+	{"SHA256SUMS", []byte(sha256Hex(testImage) + "  images/server.img\n")},
+}
+
+// buildArchive packs files as format: "zip", "tar", "tar.gz" or "tar.xz".
+func buildArchive(t *testing.T, format string, files []archiveFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if format == "zip" {
+		w := zip.NewWriter(&buf)
+		w.Create("images/") // Directories are skipped
+		for _, f := range files {
+			fw, err := w.Create(f.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fw.Write(f.data)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	var tarData bytes.Buffer
+	tw := tar.NewWriter(&tarData)
+	tw.WriteHeader(&tar.Header{Name: "images/", Typeflag: tar.TypeDir, Mode: 0755})
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.data))}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write(f.data)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	switch format {
+	case "tar":
+		return tarData.Bytes()
+	case "tar.gz":
+		w := gzip.NewWriter(&buf)
+		w.Write(tarData.Bytes())
+		w.Close()
+	case "tar.xz":
+		w, err := xz.NewWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(tarData.Bytes())
+		w.Close()
+	}
+	return buf.Bytes()
+}
+
+func TestMatchMember(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"images/server.img", "images/server.img", true},
+		{"images/server.img", "./images/server.img", true},
+		{"server.img", "images/server.img", true},
+		{"*.img", "images/server.img", true},
+		{"images/*.img", "images/server.img", true},
+		{"*/*.img", "images/server.img", true},
+		{"other/*.img", "images/server.img", false},
+		{"*.img", "images/boot.raw", false},
+		{"[", "images/server.img", false},
+	}
+	for _, tt := range tests {
+		if got := matchMember(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchMember(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadImageArchive(t *testing.T) {
+	tests := []struct {
+		name   string
+		member string
+		want   []byte
+	}{
+		{"largest image", "", testImage},
+		{"exact", "images/boot.raw", bundleFiles[1].data},
+		{"glob", "*.raw", bundleFiles[1].data},
+		{"text file", "README.md", bundleFiles[0].data},
+	}
+	for _, format := range []string{"zip", "tar", "tar.gz", "tar.xz"} {
+		server := serveBytes(t, buildArchive(t, format, bundleFiles))
+		for _, tt := range tests {
+			t.Run(format+"/"+tt.name, func(t *testing.T) {
+				result, err := DownloadImage(context.Background(), server.URL+"/bundle."+format, &DownloadOptions{
+					DestDir:        t.TempDir(),
+					ExpectedSHA256: sha256Hex(tt.want),
+					ArchiveMember:  tt.member,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if filepath.Base(result.Path) != "bundle" {
+					t.Errorf("image saved as %s, want bundle", filepath.Base(result.Path))
+				}
+				data, err := os.ReadFile(result.Path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(data, tt.want) {
+					t.Errorf("extracted %d bytes, want %d", len(data), len(tt.want))
+				}
+			})
+		}
+	}
+}
+
+func TestDownloadImageArchiveErrors(t *testing.T) {
+	noImage := []archiveFile{
+		{"README.md", []byte("readme")},
+		{"firmware.bin", testImage},
+	}
+	tests := []struct {
+		name    string
+		files   []archiveFile
+		member  string
+		wantErr []string
+	}{
+		{"no match", bundleFiles, "*.iso", []string{`"*.iso"`, "images/server.img", "SHA256SUMS"}},
+		{"no image", noImage, "", []string{"archive_member", "README.md", "firmware.bin"}},
+		{"empty", nil, "", []string{"empty"}},
+	}
+	for _, format := range []string{"zip", "tar.gz"} {
+		for _, tt := range tests {
+			t.Run(format+"/"+tt.name, func(t *testing.T) {
+				server := serveBytes(t, buildArchive(t, format, tt.files))
+				dir := t.TempDir()
+				_, err := DownloadImage(context.Background(), server.URL+"/bundle."+format, &DownloadOptions{
+					DestDir:       dir,
+					ArchiveMember: tt.member,
+				})
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				for _, want := range tt.wantErr {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("error %q does not mention %s", err, want)
+					}
+				}
+				if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+					t.Errorf("%d files left behind", len(entries))
+				}
+			})
+		}
+	}
+}
+
+func TestDecompressImageArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bundle.tgz")
+	if err := os.WriteFile(path, buildArchive(t, "tar.gz", bundleFiles), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := DecompressImage(context.Background(), path, &DownloadOptions{ArchiveMember: "images/*.img"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(result.Path)
+	if result.SHA256 != sha256Hex(testImage) {
+		t.Errorf("SHA256 = %s, want %s", result.SHA256, sha256Hex(testImage))
+	}
+}
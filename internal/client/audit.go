@@ -0,0 +1,265 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	tpi "github.com/davidroman0O/tpi/client"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Audit outcomes.
+const (
+	AuditOutcomeSuccess   = "success"
+	AuditOutcomeFailure   = "failure"
+	AuditOutcomeCancelled = "cancelled"
+)
+
+// AuditRecord is one line of the audit log.
+type AuditRecord struct {
+	Time       time.Time              `json:"time"`
+	Host       string                 `json:"host"`
+	User       string                 `json:"user"`               // Local user running Terraform
+	BMCUser    string                 `json:"bmc_user,omitempty"` // Empty when authenticated by token
+	Workspace  string                 `json:"workspace"`
+	Node       int                    `json:"node,omitempty"`
+	Operation  string                 `json:"operation"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	DurationMS int64                  `json:"duration_ms"`
+	Outcome    string                 `json:"outcome"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// auditLog appends an AuditRecord for every mutating operation to a JSON
+// lines file. A nil *auditLog records nothing.
+type auditLog struct {
+	path      string
+	host      string
+	bmcUser   string
+	user      string
+	workspace string
+
+	mu sync.Mutex // Serializes appends from parallel resources
+}
+
+// newAuditLog returns an audit log appending to path, or nil when path is
+// empty. It fails when the file cannot be opened for appending, so a broken
+// audit trail is caught before anything is changed.
+func newAuditLog(path, host, bmcUser string) (*auditLog, error) {
+	if path == "" {
+		return nil, nil
+	}
+	path, err := expandHome(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := openAuditFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+
+	return &auditLog{
+		path:      path,
+		host:      host,
+		bmcUser:   bmcUser,
+		user:      currentUser(),
+		workspace: terraformWorkspace(),
+	}, nil
+}
+
+func openAuditFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return file, nil
+}
+
+// auditEntry is an operation whose record is written when it ends.
+type auditEntry struct {
+	log    *auditLog
+	ctx    context.Context
+	start  time.Time
+	record AuditRecord
+}
+
+// begin starts auditing operation on node, or on the whole board when node
+// is 0. Secrets in params are redacted before they are written.
+func (l *auditLog) begin(ctx context.Context, operation string, node int, params map[string]interface{}) *auditEntry {
+	if l == nil {
+		return nil
+	}
+	return &auditEntry{
+		log:   l,
+		ctx:   ctx,
+		start: time.Now(),
+		record: AuditRecord{
+			Host:       l.host,
+			User:       l.user,
+			BMCUser:    l.bmcUser,
+			Workspace:  l.workspace,
+			Node:       node,
+			Operation:  operation,
+			Parameters: redactParams(params),
+		},
+	}
+}
+
+// end writes the record with the outcome of *err. It is meant to be
+// deferred with a pointer to the operation's named error result. The
+// operation has already happened, so a failed write is logged rather than
+// returned.
+func (e *auditEntry) end(err *error) {
+	if e == nil {
+		return
+	}
+	e.record.Time = e.start.UTC()
+	e.record.DurationMS = time.Since(e.start).Milliseconds()
+	e.record.Outcome = AuditOutcomeSuccess
+	if *err != nil {
+		e.record.Outcome = AuditOutcomeFailure
+		if errors.Is(*err, context.Canceled) || errors.Is(*err, context.DeadlineExceeded) {
+			e.record.Outcome = AuditOutcomeCancelled
+		}
+		e.record.Error = (*err).Error()
+	}
+
+	if writeErr := e.log.write(e.record); writeErr != nil {
+		tflog.Error(e.ctx, "Failed to write audit log", map[string]interface{}{
+			"path":      e.log.path,
+			"operation": e.record.Operation,
+			"error":     writeErr.Error(),
+		})
+	}
+}
+
+// write appends record as one line and syncs it to disk.
+func (l *auditLog) write(record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := openAuditFile(l.path)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// flashParams returns the audited parameters of a flash.
+func flashParams(options *tpi.FlashOptions) map[string]interface{} {
+	if options == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"image_path": options.ImagePath,
+		"sha256":     options.SHA256,
+		"skip_crc":   options.SkipCRC,
+	}
+}
+
+// secretWords mark parameter and URL query names whose values are redacted.
+var secretWords = []string{"password", "passphrase", "secret", "token", "private_key", "signature", "credential"}
+
+func isSecretName(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range secretWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactParams returns a copy of params with the values of secret-looking
+// names replaced, and passwords and secret query values removed from URLs.
+func redactParams(params map[string]interface{}) map[string]interface{} {
+	if len(params) == 0 {
+		return nil
+	}
+	out := make(map[string]interface{}, len(params))
+	for name, value := range params {
+		switch {
+		case isSecretName(name):
+			out[name] = "REDACTED"
+		default:
+			if s, ok := value.(string); ok {
+				value = redactURL(s)
+			}
+			out[name] = value
+		}
+	}
+	return out
+}
+
+// redactURL hides the password and secret query values of an absolute URL.
+// Other strings are returned unchanged.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return s
+	}
+	query := u.Query()
+	redacted := false
+	for name := range query {
+		if isSecretName(name) {
+			query.Set(name, "REDACTED")
+			redacted = true
+		}
+	}
+	if redacted {
+		u.RawQuery = query.Encode()
+	}
+	return u.Redacted()
+}
+
+// currentUser returns the name of the local user running Terraform.
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// terraformWorkspace returns the selected Terraform workspace. Terraform does
+// not pass it to providers, but the provider runs in the working directory,
+// where the data directory records the selection.
+func terraformWorkspace() string {
+	if workspace := os.Getenv("TF_WORKSPACE"); workspace != "" {
+		return workspace
+	}
+	dataDir := os.Getenv("TF_DATA_DIR")
+	if dataDir == "" {
+		dataDir = ".terraform"
+	}
+	data, err := os.ReadFile(filepath.Join(dataDir, "environment"))
+	if err != nil || strings.TrimSpace(string(data)) == "" {
+		return "default"
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,62 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+
+	tpi "github.com/davidroman0O/tpi/client"
+)
+
+// BMC is the set of board operations resources and data sources rely on.
+// *Client implements it against a real BMC; tests use an in-memory fake.
+// Every operation takes a context that aborts it when done.
+type BMC interface {
+	// Address returns the BMC host the operations are sent to.
+	Address() string
+	// ReadOnly reports whether mutating operations are refused.
+	ReadOnly() bool
+	// DefaultCacheLocation returns the cache used by flashes that do not choose one.
+	DefaultCacheLocation() string
+
+	// Session
+	NewSessionContext(ctx context.Context) (string, error)
+
+	// Power
+	PowerStatusContext(ctx context.Context) (map[int]bool, error)
+	PowerOnContext(ctx context.Context, node int) error
+	PowerOffContext(ctx context.Context, node int) error
+
+	// USB
+	UsbGetStatusContext(ctx context.Context) (*tpi.UsbStatusInfo, error)
+	UsbSetHostContext(ctx context.Context, node int, bmc bool) error
+	UsbSetDeviceContext(ctx context.Context, node int, bmc bool) error
+	UsbSetFlashContext(ctx context.Context, node int, bmc bool) error
+
+	// Info
+	InfoContext(ctx context.Context) (map[string]string, error)
+	AboutContext(ctx context.Context) (map[string]string, error)
+
+	// Flash
+	FlashNodeContext(ctx context.Context, node int, options *tpi.FlashOptions) error
+	FlashNodeLocalContext(ctx context.Context, node int, imagePath string) error
+
+	// SFTP and exec
+	UploadFileContext(ctx context.Context, localPath, remotePath string) error
+	ListDirectoryContext(ctx context.Context, remotePath string) ([]tpi.FileInfo, error)
+	RunContext(ctx context.Context, cmd Command) (*CommandResult, error)
+}
+
+// Ensure Client satisfies BMC.
+var _ BMC = &Client{}
+
+// Address returns the BMC host the client talks to.
+func (c *Client) Address() string {
+	return c.Host
+}
+
+// DefaultCacheLocation returns the cache used by flashes that do not choose one.
+func (c *Client) DefaultCacheLocation() string {
+	return c.DefaultCache
+}
@@ -4,6 +4,7 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -24,12 +25,12 @@ const (
 
 // ImageCache manages cached images for the Turing Pi provider.
 type ImageCache struct {
-	client   *Client
+	client   BMC
 	localDir string
 }
 
 // NewImageCache creates a new image cache manager.
-func NewImageCache(client *Client) (*ImageCache, error) {
+func NewImageCache(client BMC) (*ImageCache, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get home directory: %w", err)
@@ -47,12 +48,18 @@ func NewImageCache(client *Client) (*ImageCache, error) {
 }
 
 // GetCachedImagePath returns the path to a cached image, or empty string if not cached.
-func (c *ImageCache) GetCachedImagePath(sha256 string, location string) (string, error) {
+func (c *ImageCache) GetCachedImagePath(ctx context.Context, sha256 string, location string) (path string, err error) {
+	ctx, span := startSpan(ctx, "cache.lookup", attrCacheLocation.String(location))
+	defer func() {
+		span.SetAttributes(attrCacheHit.Bool(path != ""))
+		endSpan(span, err)
+	}()
+
 	switch location {
 	case CacheLocationLocal:
 		return c.getLocalCachePath(sha256)
 	case CacheLocationBMC:
-		return c.getBMCCachePath(sha256)
+		return c.getBMCCachePath(ctx, sha256)
 	case CacheLocationNone:
 		return "", nil
 	default:
@@ -62,12 +69,15 @@ func (c *ImageCache) GetCachedImagePath(sha256 string, location string) (string,
 
 // CacheImage stores an image in the specified cache location.
 // Returns the path where the image was cached.
-func (c *ImageCache) CacheImage(localPath, sha256, location string) (string, error) {
+func (c *ImageCache) CacheImage(ctx context.Context, localPath, sha256, location string) (_ string, err error) {
+	ctx, span := startSpan(ctx, "cache.store", attrCacheLocation.String(location))
+	defer func() { endSpan(span, err) }()
+
 	switch location {
 	case CacheLocationLocal:
 		return c.cacheLocally(localPath, sha256)
 	case CacheLocationBMC:
-		return c.cacheToBMC(localPath, sha256)
+		return c.cacheToBMC(ctx, localPath, sha256)
 	case CacheLocationNone:
 		return localPath, nil
 	default:
@@ -75,6 +85,12 @@ func (c *ImageCache) CacheImage(localPath, sha256, location string) (string, err
 	}
 }
 
+// PartialDir returns the directory where interrupted downloads are kept to
+// be resumed.
+func (c *ImageCache) PartialDir() string {
+	return filepath.Join(c.localDir, "partial")
+}
+
 // getLocalCachePath checks if an image exists in the local cache.
 func (c *ImageCache) getLocalCachePath(sha256 string) (string, error) {
 	path := filepath.Join(c.localDir, sha256+".img")
@@ -88,10 +104,13 @@ func (c *ImageCache) getLocalCachePath(sha256 string) (string, error) {
 }
 
 // getBMCCachePath checks if an image exists in the BMC cache.
-func (c *ImageCache) getBMCCachePath(sha256 string) (string, error) {
+func (c *ImageCache) getBMCCachePath(ctx context.Context, sha256 string) (string, error) {
 	remotePath := fmt.Sprintf("%s/%s.img", bmcCacheDir, sha256)
 
-	files, err := c.client.ListDirectory(bmcCacheDir)
+	files, err := c.client.ListDirectoryContext(ctx, bmcCacheDir)
+	if IsHostKeyError(err) || ctx.Err() != nil {
+		return "", err
+	}
 	if err != nil {
 		// Directory might not exist yet
 		return "", nil
@@ -131,33 +150,36 @@ func (c *ImageCache) cacheLocally(srcPath, sha256 string) (string, error) {
 
 	if _, err := io.Copy(dst, src); err != nil {
 		os.Remove(destPath)
-		return "", fmt.Errorf("failed to copy to cache: %w", err)
+		return "", fmt.Errorf("failed to copy to cache: %w", spaceError(err))
 	}
 
 	return destPath, nil
 }
 
 // cacheToBMC uploads an image to the BMC cache.
-func (c *ImageCache) cacheToBMC(localPath, sha256 string) (string, error) {
+func (c *ImageCache) cacheToBMC(ctx context.Context, localPath, sha256 string) (string, error) {
 	remotePath := fmt.Sprintf("%s/%s.img", bmcCacheDir, sha256)
 
 	// Ensure cache directory exists on BMC
-	_, err := c.client.ExecuteCommand(fmt.Sprintf("mkdir -p %s", bmcCacheDir))
+	mkdir := NewCommand("mkdir", "-p", "--", bmcCacheDir)
+	mkdir.Idempotent = true
+	_, err := c.client.RunContext(ctx, mkdir)
 	if err != nil {
 		return "", fmt.Errorf("failed to create BMC cache directory: %w", err)
 	}
 
 	// Check if already cached
-	existingPath, err := c.getBMCCachePath(sha256)
+	existingPath, err := c.getBMCCachePath(ctx, sha256)
 	if err != nil {
 		return "", err
 	}
 	if existingPath != "" {
+		setSpanAttributes(ctx, attrCacheHit.Bool(true))
 		return existingPath, nil
 	}
 
 	// Upload to BMC
-	if err := c.client.UploadFile(localPath, remotePath); err != nil {
+	if err := c.client.UploadFileContext(ctx, localPath, remotePath); err != nil {
 		return "", fmt.Errorf("failed to upload to BMC: %w", err)
 	}
 
@@ -184,8 +206,8 @@ func (c *ImageCache) CleanLocalCache() error {
 }
 
 // CleanBMCCache removes all cached images from the BMC cache.
-func (c *ImageCache) CleanBMCCache() error {
-	_, err := c.client.ExecuteCommand(fmt.Sprintf("rm -rf %s", bmcCacheDir))
+func (c *ImageCache) CleanBMCCache(ctx context.Context) error {
+	_, err := c.client.RunContext(ctx, NewCommand("rm", "-rf", "--", bmcCacheDir))
 	if err != nil {
 		return fmt.Errorf("failed to clean BMC cache: %w", err)
 	}
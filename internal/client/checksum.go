@@ -0,0 +1,158 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// maxChecksumFileSize bounds checksum and signature downloads, which are a
+// few kilobytes even for release pages listing hundreds of files.
+const maxChecksumFileSize = 1 << 20
+
+// ChecksumOptions locates the published checksum of an image.
+type ChecksumOptions struct {
+	URL          string   // SHA256SUMS-style file listing the image
+	SignatureURL string   // Optional: detached OpenPGP or minisign signature of the checksum file
+	TrustedKeys  []string // Armored OpenPGP or minisign public keys; required with SignatureURL
+}
+
+// FetchChecksum downloads the checksum file at opts.URL and returns the
+// SHA256 it lists for the file name. With a SignatureURL, the checksum file
+// must carry a valid signature by one of opts.TrustedKeys; otherwise the
+// error wraps ErrSignatureInvalid.
+//
+// GNU ("<sha256>  name" or "<sha256> *name") and BSD ("SHA256 (name) =
+// <sha256>") lines are understood, and entries are matched by their full
+// path or base name. A file holding a single bare digest applies to any name.
+func FetchChecksum(ctx context.Context, name string, opts *ChecksumOptions) (_ string, err error) {
+	ctx, span := startSpan(ctx, "checksum.fetch", attrURL.String(redactURL(opts.URL)))
+	defer func() { endSpan(span, err) }()
+
+	data, err := fetchSmallFile(ctx, opts.URL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download checksum file: %w", err)
+	}
+
+	if opts.SignatureURL != "" {
+		signature, err := fetchSmallFile(ctx, opts.SignatureURL)
+		if err != nil {
+			return "", fmt.Errorf("failed to download signature: %w", err)
+		}
+		scheme, err := verifySignature(data, signature, opts.TrustedKeys)
+		span.SetAttributes(attrSignature.String(scheme))
+		if err != nil {
+			return "", fmt.Errorf("checksum file %s: %w", redactURL(opts.URL), err)
+		}
+	}
+
+	sum, ok := findChecksum(data, name)
+	if !ok {
+		return "", fmt.Errorf("checksum file %s lists no SHA256 for %s", redactURL(opts.URL), name)
+	}
+	return sum, nil
+}
+
+// ImageFileName returns the file name a checksum file lists for the image at
+// rawURL: the last element of its path, unescaped.
+func ImageFileName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return path.Base(rawURL)
+	}
+	return path.Base(u.Path)
+}
+
+// findChecksum returns the SHA256 listed for name in a checksum file.
+func findChecksum(data []byte, name string) (string, bool) {
+	var bare []string
+	var byBase string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		sum, entry, ok := parseChecksumLine(scanner.Text())
+		switch {
+		case !ok:
+			continue
+		case entry == "":
+			bare = append(bare, sum)
+		case entry == name:
+			return sum, true
+		case path.Base(entry) == name && byBase == "":
+			byBase = sum
+		}
+	}
+	if byBase != "" {
+		return byBase, true
+	}
+	if len(bare) == 1 {
+		return bare[0], true
+	}
+	return "", false
+}
+
+// parseChecksumLine parses one SHA256 line of a checksum file, returning an
+// empty entry for a bare digest. Comments, other algorithms and the armor of
+// clear-signed files are not SHA256 lines.
+func parseChecksumLine(line string) (sum, entry string, ok bool) {
+	line = strings.TrimSpace(line)
+
+	// BSD style: SHA256 (name) = digest
+	if rest, found := strings.CutPrefix(line, "SHA256 ("); found {
+		entry, sum, found = strings.Cut(rest, ") = ")
+		if !found || !isSHA256(sum) {
+			return "", "", false
+		}
+		return strings.ToLower(sum), strings.TrimPrefix(entry, "./"), true
+	}
+
+	// GNU style: digest, then two spaces or " *" before the name
+	sum, entry, _ = strings.Cut(line, " ")
+	if !isSHA256(sum) {
+		return "", "", false
+	}
+	entry = strings.TrimPrefix(strings.TrimLeft(entry, " "), "*")
+	return strings.ToLower(sum), strings.TrimPrefix(entry, "./"), true
+}
+
+func isSHA256(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// fetchSmallFile downloads a file of at most maxChecksumFileSize bytes.
+func fetchSmallFile(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d", redactURL(url), resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxChecksumFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxChecksumFileSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", redactURL(url), maxChecksumFileSize)
+	}
+	return data, nil
+}
@@ -0,0 +1,247 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Digests listed in testdata/SHA256SUMS, which testdata/SHA256SUMS.asc and
+// SHA256SUMS.sig sign with the gpg key in testdata/release-key.asc.
+const (
+	fixtureSHA256    = "d5f84fc8bbe9eef8c05a13f57016490d8eb42a4d8a13726edad6a73d786d1bd1"
+	fixtureZstSHA256 = "c54929b3622a49883ece460cfe0be7c74d7bcdcb3d6dedd87c55ff8bdf84fe0b"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// serveFiles serves files by path.
+func serveFiles(t *testing.T, files map[string][]byte) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// minisignSign signs data as minisign does, prehashed unless legacy, and
+// returns the signature file and the public key file.
+func minisignSign(t *testing.T, data []byte, legacy bool) (signature []byte, publicKey string) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	algorithm, message := "ED", data
+	if legacy {
+		algorithm = "Ed"
+	} else {
+		sum := blake2b.Sum512(data)
+		message = sum[:]
+	}
+	sig := ed25519.Sign(private, message)
+	trustedComment := "timestamp:1760000000\tfile:SHA256SUMS\thashed"
+	globalSig := ed25519.Sign(private, append(append([]byte{}, sig...), trustedComment...))
+
+	encode := func(parts ...[]byte) string {
+		return base64.StdEncoding.EncodeToString(bytes.Join(parts, nil))
+	}
+	signature = []byte("untrusted comment: signature from minisign secret key\n" +
+		encode([]byte(algorithm), id, sig) + "\n" +
+		"trusted comment: " + trustedComment + "\n" +
+		encode(globalSig) + "\n")
+	publicKey = "untrusted comment: minisign public key\n" + encode([]byte("Ed"), id, public) + "\n"
+	return signature, publicKey
+}
+
+// otherOpenPGPKey returns an armored OpenPGP public key that signed nothing.
+func otherOpenPGPKey(t *testing.T) string {
+	t.Helper()
+	entity, err := openpgp.NewEntity("Someone Else", "", "else@example.com", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity.Serialize(w)
+	w.Close()
+	return buf.String()
+}
+
+func TestFindChecksum(t *testing.T) {
+	sum := strings.Repeat("ab", 32)
+	other := strings.Repeat("cd", 32)
+	tests := []struct {
+		name, file, entry string
+		want              string
+	}{
+		{"gnu text", sum + "  image.img.xz\n" + other + "  image.img\n", "image.img.xz", sum},
+		{"gnu binary", other + " *other.img\n" + sum + " *image.img.xz\n", "image.img.xz", sum},
+		{"bsd", "SHA512 (image.img.xz) = 00\nSHA256 (image.img.xz) = " + sum + "\n", "image.img.xz", sum},
+		{"uppercase digest", strings.ToUpper(sum) + "  image.img.xz\n", "image.img.xz", sum},
+		{"subdirectory", other + "  ./other/image.img.xz\n", "image.img.xz", other},
+		{"exact before base name", other + "  old/image.img.xz\n" + sum + "  image.img.xz\n", "image.img.xz", sum},
+		{"bare digest", sum + "\n", "image.img.xz", sum},
+		{"crlf", sum + "  image.img.xz\r\n", "image.img.xz", sum},
+		{"clear-signed", "-----BEGIN PGP SIGNED MESSAGE-----\nHash: SHA256\n\n" + sum + "  image.img.xz\n-----BEGIN PGP SIGNATURE-----\n", "image.img.xz", sum},
+		{"missing", sum + "  image.img\n", "image.img.xz", ""},
+		{"several bare digests", sum + "\n" + other + "\n", "image.img.xz", ""},
+		{"comment", "# " + sum + "  image.img.xz\n", "image.img.xz", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := findChecksum([]byte(tt.file), tt.entry)
+			if got != tt.want || ok != (tt.want != "") {
+				t.Errorf("findChecksum = %q, %v; want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchChecksum(t *testing.T) {
+	sums := readTestdata(t, "SHA256SUMS")
+	releaseKey := string(readTestdata(t, "release-key.asc"))
+	minisignSig, minisignKey := minisignSign(t, sums, false)
+	legacySig, legacyKey := minisignSign(t, sums, true)
+	_, otherMinisignKey := minisignSign(t, sums, false)
+	server := serveFiles(t, map[string][]byte{
+		"/SHA256SUMS":          sums,
+		"/SHA256SUMS.asc":      readTestdata(t, "SHA256SUMS.asc"),
+		"/SHA256SUMS.sig":      readTestdata(t, "SHA256SUMS.sig"),
+		"/SHA256SUMS.minisig":  minisignSig,
+		"/SHA256SUMS.legacy":   legacySig,
+		"/tampered/SHA256SUMS": bytes.Replace(sums, []byte("d5f8"), []byte("0000"), 1),
+	})
+
+	tests := []struct {
+		name      string
+		file      string
+		signature string
+		keys      []string
+		want      string
+		wantErr   string // Substring of the error, which wraps ErrSignatureInvalid unless it starts with "!"
+	}{
+		{"unsigned", "/SHA256SUMS", "", nil, fixtureSHA256, ""},
+		{"armored openpgp", "/SHA256SUMS", "/SHA256SUMS.asc", []string{releaseKey}, fixtureSHA256, ""},
+		{"binary openpgp", "/SHA256SUMS", "/SHA256SUMS.sig", []string{minisignKey, releaseKey}, fixtureSHA256, ""},
+		{"minisign", "/SHA256SUMS", "/SHA256SUMS.minisig", []string{releaseKey, otherMinisignKey, minisignKey}, fixtureSHA256, ""},
+		{"legacy minisign", "/SHA256SUMS", "/SHA256SUMS.legacy", []string{legacyKey}, fixtureSHA256, ""},
+		{"tampered openpgp", "/tampered/SHA256SUMS", "/SHA256SUMS.asc", []string{releaseKey}, "", "invalid signature"},
+		{"tampered minisign", "/tampered/SHA256SUMS", "/SHA256SUMS.minisig", []string{minisignKey}, "", "does not match"},
+		{"untrusted openpgp key", "/SHA256SUMS", "/SHA256SUMS.asc", []string{otherOpenPGPKey(t)}, "", "invalid signature"},
+		{"untrusted minisign key", "/SHA256SUMS", "/SHA256SUMS.minisig", []string{otherMinisignKey}, "", "not in trusted_keys"},
+		{"no openpgp key", "/SHA256SUMS", "/SHA256SUMS.asc", []string{minisignKey}, "", "no armored OpenPGP public key"},
+		{"missing signature", "/SHA256SUMS", "/SHA256SUMS.gpg", []string{releaseKey}, "", "!status 404"},
+		{"missing checksum file", "/SHA256SUMS.txt", "", nil, "", "!status 404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &ChecksumOptions{URL: server.URL + tt.file, TrustedKeys: tt.keys}
+			if tt.signature != "" {
+				opts.SignatureURL = server.URL + tt.signature
+			}
+			got, err := FetchChecksum(context.Background(), "image.img", opts)
+			if tt.wantErr == "" {
+				if err != nil || got != tt.want {
+					t.Fatalf("FetchChecksum = %q, %v; want %q", got, err, tt.want)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("FetchChecksum = %q, want an error", got)
+			}
+			wantErr, plain := strings.CutPrefix(tt.wantErr, "!")
+			if !strings.Contains(err.Error(), wantErr) {
+				t.Errorf("error %q does not mention %q", err, wantErr)
+			}
+			if errors.Is(err, ErrSignatureInvalid) == plain {
+				t.Errorf("errors.Is(%v, ErrSignatureInvalid) = %v", err, !plain)
+			}
+		})
+	}
+}
+
+func TestFetchChecksumNotListed(t *testing.T) {
+	server := serveFiles(t, map[string][]byte{"/SHA256SUMS": readTestdata(t, "SHA256SUMS")})
+	_, err := FetchChecksum(context.Background(), "image.img.xz", &ChecksumOptions{URL: server.URL + "/SHA256SUMS"})
+	if err == nil || !strings.Contains(err.Error(), "lists no SHA256 for image.img.xz") {
+		t.Errorf("err = %v, want the missing entry named", err)
+	}
+}
+
+func TestImageFileName(t *testing.T) {
+	tests := map[string]string{
+		"https://example.com/releases/v1.2/image.img.xz":              "image.img.xz",
+		"https://example.com/image.img.xz?X-Amz-Signature=abc":        "image.img.xz",
+		"https://example.com/Armbian%2025.5%20server.img.xz#fragment": "Armbian 25.5 server.img.xz",
+	}
+	for url, want := range tests {
+		if got := ImageFileName(url); got != want {
+			t.Errorf("ImageFileName(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestDownloadImageExpectedCompressedSHA256(t *testing.T) {
+	compressed := readTestdata(t, "image.img.zst")
+	server := serveBytes(t, compressed)
+
+	result, err := DownloadImage(context.Background(), server.URL+"/image.img.zst", &DownloadOptions{
+		DestDir:                  t.TempDir(),
+		ExpectedCompressedSHA256: fixtureZstSHA256,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SHA256 != fixtureSHA256 {
+		t.Errorf("SHA256 = %s, want %s", result.SHA256, fixtureSHA256)
+	}
+
+	dir := t.TempDir()
+	_, err = DownloadImage(context.Background(), server.URL+"/image.img.zst", &DownloadOptions{
+		DestDir:                  dir,
+		ExpectedCompressedSHA256: fixtureSHA256,
+	})
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("err = %v, want ErrChecksumMismatch", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("%d files left behind", len(entries))
+	}
+}
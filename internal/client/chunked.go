@@ -0,0 +1,166 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// downloadChunkSize is the size of the ranges a parallel download requests.
+// At most two chunks per connection are held in memory.
+const downloadChunkSize = 8 << 20
+
+// chunkedReader reads bytes [start, end) of a download as consecutive chunks
+// fetched over several connections, and returns them in order. The first
+// chunk is read from the response that is already open.
+type chunkedReader struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	results []chan chunkResult // One per chunk, in order
+	slots   chan struct{}      // Bounds the chunks fetched but not yet read
+	next    int
+	buf     []byte
+}
+
+type chunkResult struct {
+	data []byte
+	err  error
+}
+
+// parallelizable reports whether the rest of resp can be downloaded in
+// ranged chunks, each validated against the content of resp.
+func parallelizable(resp *http.Response) bool {
+	if resp.Uncompressed || resp.ContentLength <= downloadChunkSize {
+		return false
+	}
+	if resp.StatusCode != http.StatusPartialContent && resp.Header.Get("Accept-Ranges") != "bytes" {
+		return false
+	}
+	return contentValidator(resp) != ""
+}
+
+// contentValidator returns the If-Range value identifying the content of resp.
+func contentValidator(resp *http.Response) string {
+	state := partialState{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+	return state.validator()
+}
+
+// newChunkedReader starts fetching bytes [start, end) of url over connections
+// connections. first is the open response body positioned at start; it is
+// closed once its chunk has been read.
+func newChunkedReader(ctx context.Context, url, validator string, first io.ReadCloser, start, end int64, connections int) *chunkedReader {
+	ctx, cancel := context.WithCancel(ctx)
+	count := int((end - start + downloadChunkSize - 1) / downloadChunkSize)
+	r := &chunkedReader{
+		ctx:     ctx,
+		cancel:  cancel,
+		results: make([]chan chunkResult, count),
+		slots:   make(chan struct{}, 2*connections),
+	}
+	for i := range r.results {
+		r.results[i] = make(chan chunkResult, 1)
+	}
+
+	fetchChunk := func(i int) ([]byte, error) {
+		from := start + int64(i)*downloadChunkSize
+		to := min(from+downloadChunkSize, end)
+		if i == 0 {
+			defer first.Close()
+			data := make([]byte, to-from)
+			if _, err := io.ReadFull(first, data); err != nil {
+				return nil, fmt.Errorf("failed to download bytes %d-%d: %w", from, to-1, err)
+			}
+			return data, nil
+		}
+		return fetchRange(ctx, url, validator, from, to)
+	}
+
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		for i := range r.results {
+			select {
+			case r.slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for w := 0; w < connections; w++ {
+		go func() {
+			for i := range jobs {
+				data, err := fetchChunk(i)
+				r.results[i] <- chunkResult{data: data, err: err}
+			}
+		}()
+	}
+	return r
+}
+
+// fetchRange downloads bytes [from, to) of url, failing if the content no
+// longer matches validator.
+func fetchRange(ctx context.Context, url, validator string, from, to int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", from, to-1))
+	req.Header.Set("If-Range", validator)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download bytes %d-%d: %w", from, to-1, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("download of bytes %d-%d failed with status %d; the content may have changed during the download", from, to-1, resp.StatusCode)
+	}
+	if start, _, ok := parseContentRange(resp.Header.Get("Content-Range")); !ok || start != from {
+		return nil, fmt.Errorf("server returned range %q for bytes %d-%d", resp.Header.Get("Content-Range"), from, to-1)
+	}
+
+	data := make([]byte, to-from)
+	if _, err := io.ReadFull(resp.Body, data); err != nil {
+		return nil, fmt.Errorf("failed to download bytes %d-%d: %w", from, to-1, err)
+	}
+	return data, nil
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.next == len(r.results) {
+			return 0, io.EOF
+		}
+		select {
+		case result := <-r.results[r.next]:
+			<-r.slots
+			if result.err != nil {
+				return 0, result.err
+			}
+			r.buf = result.data
+			r.next++
+		case <-r.ctx.Done():
+			return 0, r.ctx.Err()
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// Close stops the fetches still in flight.
+func (r *chunkedReader) Close() error {
+	r.cancel()
+	return nil
+}
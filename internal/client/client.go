@@ -4,114 +4,538 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	tpi "github.com/davidroman0O/tpi/client"
 )
 
+// Config holds the settings used to build a Client.
+type Config struct {
+	Host     string
+	Username string
+	Password string
+	Token    string // Bearer token of an existing BMC session, used instead of logging in
+
+	SSHUser           string
+	SSHPassword       string
+	SSHPort           int
+	SSHPrivateKey     string // PEM-encoded private key
+	SSHPrivateKeyPath string // Path to a PEM-encoded private key
+	SSHUseAgent       bool   // Use the agent listening on SSH_AUTH_SOCK
+
+	SSHKnownHostsFile     string // OpenSSH known_hosts file used to verify the BMC host key
+	SSHHostKeyFingerprint string // Pinned "SHA256:..." fingerprint of the BMC host key
+
+	Scheme string // "http" or "https" (default)
+	TLS    TLSConfig
+
+	Retry RetryPolicy
+
+	BoardLockTimeout time.Duration // Wait limit for the board lock (flash, USB); default DefaultLockTimeout
+	NodeLockTimeout  time.Duration // Wait limit for per-node locks (power); default DefaultLockTimeout
+
+	DefaultCache string // Cache location used by flashes that do not choose one; default CacheLocationNone
+
+	ReadOnly bool // Reject every operation that changes the BMC or a node
+
+	AuditLogPath string // JSON lines file every mutating operation is appended to; empty disables auditing
+}
+
 // Client wraps the TPI client with additional configuration for the Terraform provider.
 type Client struct {
-	TPI         *tpi.Client
-	Host        string
-	SSHUser     string
-	SSHPassword string
-	SSHPort     int
+	Host              string
+	SSHUser           string
+	SSHPassword       string
+	SSHPort           int
+	SSHPrivateKey     string
+	SSHPrivateKeyPath string
+	SSHUseAgent       bool
+
+	SSHKnownHostsFile     string
+	SSHHostKeyFingerprint string
+
+	DefaultCache string
+
+	api         *apiClient
+	ssh         *sshPool
+	ctx         context.Context
+	retryPolicy RetryPolicy
+	locks       *LockManager
+	audit       *auditLog
+	readOnly    bool
 }
 
 // NewClient creates a new client wrapper for the Turing Pi BMC.
-func NewClient(host, username, password, sshUser, sshPassword string, sshPort int) (*Client, error) {
-	tpiClient, err := tpi.NewClient(
-		tpi.WithHost(host),
-		tpi.WithCredentials(username, password),
-	)
+// ctx supplies the logger used for retry messages; its cancellation is
+// ignored because the client outlives the provider's Configure call.
+func NewClient(ctx context.Context, cfg Config) (*Client, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("host is required")
+	}
+
+	scheme := cfg.Scheme
+	if scheme == "" {
+		scheme = SchemeHTTPS
+	}
+	if scheme != SchemeHTTP && scheme != SchemeHTTPS {
+		return nil, fmt.Errorf("unsupported scheme %q (must be %q or %q)", scheme, SchemeHTTP, SchemeHTTPS)
+	}
+
+	defaultCache := cfg.DefaultCache
+	switch defaultCache {
+	case "":
+		defaultCache = CacheLocationNone
+	case CacheLocationLocal, CacheLocationBMC, CacheLocationNone:
+	default:
+		return nil, fmt.Errorf("unknown cache location: %s", defaultCache)
+	}
+
+	tlsConfig, err := buildTLSConfig(cfg.Host, cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	api, err := newAPIClient(cfg.Host, scheme, tlsConfig, cfg.Username, cfg.Password, cfg.Token)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create TPI client: %w", err)
+		return nil, err
+	}
+
+	audit, err := newAuditLog(cfg.AuditLogPath, cfg.Host, cfg.Username)
+	if err != nil {
+		return nil, err
 	}
 
 	return &Client{
-		TPI:         tpiClient,
-		Host:        host,
-		SSHUser:     sshUser,
-		SSHPassword: sshPassword,
-		SSHPort:     sshPort,
+		api:               api,
+		ssh:               newSSHPool(),
+		ctx:               context.WithoutCancel(ctx),
+		retryPolicy:       cfg.Retry.withDefaults(),
+		locks:             NewLockManager(cfg.BoardLockTimeout, cfg.NodeLockTimeout),
+		audit:             audit,
+		readOnly:          cfg.ReadOnly,
+		Host:              cfg.Host,
+		SSHUser:           cfg.SSHUser,
+		SSHPassword:       cfg.SSHPassword,
+		SSHPort:           cfg.SSHPort,
+		SSHPrivateKey:     cfg.SSHPrivateKey,
+		SSHPrivateKeyPath: cfg.SSHPrivateKeyPath,
+		SSHUseAgent:       cfg.SSHUseAgent,
+
+		SSHKnownHostsFile:     cfg.SSHKnownHostsFile,
+		SSHHostKeyFingerprint: cfg.SSHHostKeyFingerprint,
+
+		DefaultCache: defaultCache,
 	}, nil
 }
 
-// SSHOptions returns the SSH options for SFTP operations.
-func (c *Client) SSHOptions() []tpi.SSHOption {
-	return []tpi.SSHOption{
-		tpi.WithSSHCredentials(c.SSHUser, c.SSHPassword),
-		tpi.WithSSHPort(c.SSHPort),
+// ReadOnly reports whether the client refuses mutating operations.
+func (c *Client) ReadOnly() bool {
+	return c.readOnly
+}
+
+// checkWritable returns ErrReadOnly, naming operation, when the client is
+// read-only.
+func (c *Client) checkWritable(operation string) error {
+	if c.readOnly {
+		return fmt.Errorf("%w: %s is not allowed", ErrReadOnly, operation)
 	}
+	return nil
+}
+
+// NewSession logs in to the BMC API and returns the bearer token of a new
+// session. The client's own session is not affected.
+func (c *Client) NewSession() (string, error) {
+	return c.NewSessionContext(c.ctx)
+}
+
+// NewSessionContext is NewSession with a context that aborts the login.
+func (c *Client) NewSessionContext(ctx context.Context) (_ string, err error) {
+	ctx, span := startSpan(ctx, "client.NewSession")
+	defer func() { endSpan(span, err) }()
+
+	return withRetry(ctx, c, "NewSession", func(ctx context.Context) (string, error) {
+		return c.api.authenticate(ctx)
+	})
 }
 
 // PowerStatus returns the power status of all nodes.
 // Returns a map of node number (1-4) to power state (true = on).
 func (c *Client) PowerStatus() (map[int]bool, error) {
-	return c.TPI.PowerStatus()
+	return c.PowerStatusContext(c.ctx)
+}
+
+// PowerStatusContext is PowerStatus with a context that aborts the request.
+func (c *Client) PowerStatusContext(ctx context.Context) (_ map[int]bool, err error) {
+	ctx, span := startSpan(ctx, "client.PowerStatus")
+	defer func() { endSpan(span, err) }()
+
+	body, err := withRetry(ctx, c, "PowerStatus", func(ctx context.Context) ([]byte, error) {
+		return c.api.get(ctx, "power", nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := apiResultObject(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract result: %w", err)
+	}
+
+	// The result maps node1..node4 to 0/1 (or "0"/"1" on some firmware)
+	status := make(map[int]bool)
+	for key, value := range result {
+		if !strings.HasPrefix(key, "node") {
+			continue
+		}
+		node, err := strconv.Atoi(strings.TrimPrefix(key, "node"))
+		if err != nil {
+			continue
+		}
+
+		switch v := value.(type) {
+		case float64:
+			status[node] = v > 0
+		case string:
+			status[node] = v == "1" || strings.EqualFold(v, "on")
+		}
+	}
+
+	return status, nil
 }
 
 // PowerOn turns on the specified node (1-4).
 func (c *Client) PowerOn(node int) error {
-	return c.TPI.PowerOn(node)
+	return c.PowerOnContext(c.ctx, node)
+}
+
+// PowerOnContext is PowerOn with a context that aborts the lock wait and request.
+func (c *Client) PowerOnContext(ctx context.Context, node int) (err error) {
+	ctx, span := startSpan(ctx, "client.PowerOn", attrNode.Int(node))
+	defer func() { endSpan(span, err) }()
+
+	if err := c.checkWritable("PowerOn"); err != nil {
+		return err
+	}
+	defer c.audit.begin(ctx, "PowerOn", node, nil).end(&err)
+	unlock, err := c.locks.LockNode(ctx, node, "PowerOn")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return retryCall(ctx, c, "PowerOn", func(ctx context.Context) error {
+		return c.setPower(ctx, node, true)
+	})
 }
 
 // PowerOff turns off the specified node (1-4).
 func (c *Client) PowerOff(node int) error {
-	return c.TPI.PowerOff(node)
+	return c.PowerOffContext(c.ctx, node)
+}
+
+// PowerOffContext is PowerOff with a context that aborts the lock wait and request.
+func (c *Client) PowerOffContext(ctx context.Context, node int) (err error) {
+	ctx, span := startSpan(ctx, "client.PowerOff", attrNode.Int(node))
+	defer func() { endSpan(span, err) }()
+
+	if err := c.checkWritable("PowerOff"); err != nil {
+		return err
+	}
+	defer c.audit.begin(ctx, "PowerOff", node, nil).end(&err)
+	unlock, err := c.locks.LockNode(ctx, node, "PowerOff")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return retryCall(ctx, c, "PowerOff", func(ctx context.Context) error {
+		return c.setPower(ctx, node, false)
+	})
+}
+
+// setPower sets the power state of the specified node.
+func (c *Client) setPower(ctx context.Context, node int, on bool) error {
+	if err := validateNode(node); err != nil {
+		return err
+	}
+
+	state := "0"
+	if on {
+		state = "1"
+	}
+
+	params := url.Values{}
+	params.Set(fmt.Sprintf("node%d", node), state)
+
+	if err := c.api.set(ctx, "power", params); err != nil {
+		return fmt.Errorf("power state change failed: %w", err)
+	}
+	return nil
 }
 
 // UsbGetStatus returns the current USB configuration.
 func (c *Client) UsbGetStatus() (*tpi.UsbStatusInfo, error) {
-	return c.TPI.UsbGetStatus()
+	return c.UsbGetStatusContext(c.ctx)
+}
+
+// UsbGetStatusContext is UsbGetStatus with a context that aborts the request.
+func (c *Client) UsbGetStatusContext(ctx context.Context) (_ *tpi.UsbStatusInfo, err error) {
+	ctx, span := startSpan(ctx, "client.UsbGetStatus")
+	defer func() { endSpan(span, err) }()
+
+	body, err := withRetry(ctx, c, "UsbGetStatus", func(ctx context.Context) ([]byte, error) {
+		return c.api.get(ctx, "usb", nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := apiResult(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract result: %w", err)
+	}
+
+	var entries []struct {
+		Node  string `json:"node"`
+		Mode  string `json:"mode"`
+		Route string `json:"route"`
+	}
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, fmt.Errorf("invalid USB status format: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no USB status information available")
+	}
+
+	return &tpi.UsbStatusInfo{
+		Node:  entries[0].Node,
+		Mode:  entries[0].Mode,
+		Route: entries[0].Route,
+	}, nil
 }
 
 // UsbSetHost sets the specified node to USB host mode.
 func (c *Client) UsbSetHost(node int, bmc bool) error {
-	return c.TPI.UsbSetHost(node, bmc)
+	return c.UsbSetHostContext(c.ctx, node, bmc)
+}
+
+// UsbSetHostContext is UsbSetHost with a context that aborts the lock wait and request.
+func (c *Client) UsbSetHostContext(ctx context.Context, node int, bmc bool) (err error) {
+	ctx, span := startSpan(ctx, "client.UsbSetHost", attrNode.Int(node))
+	defer func() { endSpan(span, err) }()
+
+	if err := c.checkWritable("UsbSetHost"); err != nil {
+		return err
+	}
+	defer c.audit.begin(ctx, "UsbSetHost", node, map[string]interface{}{"bmc": bmc}).end(&err)
+	unlock, err := c.locks.LockBoard(ctx, "UsbSetHost")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return retryCall(ctx, c, "UsbSetHost", func(ctx context.Context) error {
+		return c.setUsb(ctx, node, tpi.UsbHost, bmc)
+	})
 }
 
 // UsbSetDevice sets the specified node to USB device mode.
 func (c *Client) UsbSetDevice(node int, bmc bool) error {
-	return c.TPI.UsbSetDevice(node, bmc)
+	return c.UsbSetDeviceContext(c.ctx, node, bmc)
+}
+
+// UsbSetDeviceContext is UsbSetDevice with a context that aborts the lock wait and request.
+func (c *Client) UsbSetDeviceContext(ctx context.Context, node int, bmc bool) (err error) {
+	ctx, span := startSpan(ctx, "client.UsbSetDevice", attrNode.Int(node))
+	defer func() { endSpan(span, err) }()
+
+	if err := c.checkWritable("UsbSetDevice"); err != nil {
+		return err
+	}
+	defer c.audit.begin(ctx, "UsbSetDevice", node, map[string]interface{}{"bmc": bmc}).end(&err)
+	unlock, err := c.locks.LockBoard(ctx, "UsbSetDevice")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return retryCall(ctx, c, "UsbSetDevice", func(ctx context.Context) error {
+		return c.setUsb(ctx, node, tpi.UsbDevice, bmc)
+	})
 }
 
 // UsbSetFlash sets the specified node to USB flash mode.
 func (c *Client) UsbSetFlash(node int, bmc bool) error {
-	return c.TPI.UsbSetFlash(node, bmc)
+	return c.UsbSetFlashContext(c.ctx, node, bmc)
+}
+
+// UsbSetFlashContext is UsbSetFlash with a context that aborts the lock wait and request.
+func (c *Client) UsbSetFlashContext(ctx context.Context, node int, bmc bool) (err error) {
+	ctx, span := startSpan(ctx, "client.UsbSetFlash", attrNode.Int(node))
+	defer func() { endSpan(span, err) }()
+
+	if err := c.checkWritable("UsbSetFlash"); err != nil {
+		return err
+	}
+	defer c.audit.begin(ctx, "UsbSetFlash", node, map[string]interface{}{"bmc": bmc}).end(&err)
+	unlock, err := c.locks.LockBoard(ctx, "UsbSetFlash")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return retryCall(ctx, c, "UsbSetFlash", func(ctx context.Context) error {
+		return c.setUsb(ctx, node, tpi.UsbFlash, bmc)
+	})
+}
+
+// setUsb configures the USB mode and routing for the specified node.
+func (c *Client) setUsb(ctx context.Context, node int, mode tpi.UsbCmd, bmc bool) error {
+	if err := validateNode(node); err != nil {
+		return err
+	}
+
+	var modeVal int
+	switch mode {
+	case tpi.UsbHost:
+		modeVal = 0
+	case tpi.UsbDevice:
+		modeVal = 1
+	case tpi.UsbFlash:
+		modeVal = 2
+	default:
+		return fmt.Errorf("invalid USB mode: %s", mode)
+	}
+	// Bit 2 routes the USB bus through the BMC instead of the USB-A connector
+	if bmc {
+		modeVal |= 1 << 2
+	}
+
+	params := url.Values{}
+	params.Set("node", strconv.Itoa(node-1)) // BMC uses 0-based indexing
+	params.Set("mode", strconv.Itoa(modeVal))
+
+	if err := c.api.set(ctx, "usb", params); err != nil {
+		return fmt.Errorf("USB configuration failed: %w", err)
+	}
+	return nil
 }
 
 // Info returns basic BMC information.
 func (c *Client) Info() (map[string]string, error) {
-	return c.TPI.Info()
+	return c.InfoContext(c.ctx)
+}
+
+// InfoContext is Info with a context that aborts the request.
+func (c *Client) InfoContext(ctx context.Context) (_ map[string]string, err error) {
+	ctx, span := startSpan(ctx, "client.Info")
+	defer func() { endSpan(span, err) }()
+
+	body, err := withRetry(ctx, c, "Info", func(ctx context.Context) ([]byte, error) {
+		return c.api.get(ctx, "other", nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return apiResultStrings(body)
 }
 
 // About returns detailed BMC daemon information.
 func (c *Client) About() (map[string]string, error) {
-	return c.TPI.About()
+	return c.AboutContext(c.ctx)
+}
+
+// AboutContext is About with a context that aborts the request.
+func (c *Client) AboutContext(ctx context.Context) (_ map[string]string, err error) {
+	ctx, span := startSpan(ctx, "client.About")
+	defer func() { endSpan(span, err) }()
+
+	body, err := withRetry(ctx, c, "About", func(ctx context.Context) ([]byte, error) {
+		return c.api.get(ctx, "about", nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return apiResultStrings(body)
 }
 
 // FlashNode flashes an OS image to the specified node.
 func (c *Client) FlashNode(node int, options *tpi.FlashOptions) error {
-	return c.TPI.FlashNode(node, options)
+	return c.FlashNodeContext(c.ctx, node, options)
+}
+
+// FlashNodeContext is FlashNode with a context that aborts the upload or the
+// wait for completion. A flash the BMC has already started is cancelled on
+// the BMC as well.
+//
+// Only the request announcing the transfer is retried; errors during the
+// upload or while waiting for completion are returned as they are.
+func (c *Client) FlashNodeContext(ctx context.Context, node int, options *tpi.FlashOptions) (err error) {
+	ctx, span := startSpan(ctx, "client.FlashNode", attrNode.Int(node))
+	defer func() { endSpan(span, err) }()
+
+	if err := c.checkWritable("FlashNode"); err != nil {
+		return err
+	}
+	defer c.audit.begin(ctx, "FlashNode", node, flashParams(options)).end(&err)
+	if err := validateNode(node); err != nil {
+		return err
+	}
+	unlock, err := c.locks.LockBoardAndNode(ctx, node, "FlashNode")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	transfer, err := c.api.prepareFlash(ctx, node, options)
+	if err != nil {
+		return err
+	}
+	// Only announcing the transfer is retried; once the image is on its way
+	// the node may be being written, so upload and progress errors are final
+	handle, err := withRetry(ctx, c, "FlashNode", func(ctx context.Context) (int, error) {
+		return c.api.startFlash(ctx, transfer)
+	})
+	if err != nil {
+		return err
+	}
+	return c.api.runFlash(ctx, handle, transfer)
 }
 
 // FlashNodeLocal flashes an image that is already on the BMC filesystem.
 func (c *Client) FlashNodeLocal(node int, imagePath string) error {
-	return c.TPI.FlashNodeLocal(node, imagePath)
+	return c.FlashNodeLocalContext(c.ctx, node, imagePath)
 }
 
-// UploadFile uploads a local file to the BMC via SFTP.
-func (c *Client) UploadFile(localPath, remotePath string) error {
-	return c.TPI.UploadFile(localPath, remotePath, c.SSHOptions()...)
-}
+// FlashNodeLocalContext is FlashNodeLocal with a context that aborts the
+// request. A flash the BMC has already started is cancelled on the BMC as well.
+func (c *Client) FlashNodeLocalContext(ctx context.Context, node int, imagePath string) (err error) {
+	ctx, span := startSpan(ctx, "client.FlashNodeLocal", attrNode.Int(node))
+	defer func() { endSpan(span, err) }()
 
-// ListDirectory lists files in a directory on the BMC.
-func (c *Client) ListDirectory(remotePath string) ([]tpi.FileInfo, error) {
-	return c.TPI.ListDirectory(remotePath, c.SSHOptions()...)
-}
+	if err := c.checkWritable("FlashNodeLocal"); err != nil {
+		return err
+	}
+	defer c.audit.begin(ctx, "FlashNodeLocal", node, map[string]interface{}{"image_path": imagePath}).end(&err)
+	if err := validateNode(node); err != nil {
+		return err
+	}
+	unlock, err := c.locks.LockBoardAndNode(ctx, node, "FlashNodeLocal")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-// ExecuteCommand executes a command on the BMC via SSH.
-func (c *Client) ExecuteCommand(command string) (string, error) {
-	return c.TPI.ExecuteCommand(command, c.SSHOptions()...)
+	// The request starts the flash, so it is only retried when it provably
+	// never reached the BMC; a lost response must not flash the node twice
+	_, err = retryIf(ctx, c, "FlashNodeLocal", isUnsent, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, c.api.flashNodeLocal(ctx, node, imagePath)
+	})
+	return err
 }
@@ -0,0 +1,194 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tpi "github.com/davidroman0O/tpi/client"
+)
+
+func TestReadOnlyBlocksMutations(t *testing.T) {
+	bmc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("read-only client sent %s %s", r.Method, r.URL)
+	}))
+	defer bmc.Close()
+
+	c, err := NewClient(context.Background(), Config{
+		Host:     strings.TrimPrefix(bmc.URL, "http://"),
+		Scheme:   SchemeHTTP,
+		ReadOnly: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mutations := map[string]func() error{
+		"PowerOn":        func() error { return c.PowerOn(1) },
+		"PowerOff":       func() error { return c.PowerOff(1) },
+		"UsbSetHost":     func() error { return c.UsbSetHost(1, false) },
+		"UsbSetDevice":   func() error { return c.UsbSetDevice(1, false) },
+		"UsbSetFlash":    func() error { return c.UsbSetFlash(1, false) },
+		"FlashNode":      func() error { return c.FlashNode(1, &tpi.FlashOptions{ImagePath: "image.img"}) },
+		"FlashNodeLocal": func() error { return c.FlashNodeLocal(1, "/tmp/image.img") },
+		"UploadFile":     func() error { return c.UploadFile("image.img", "/tmp/image.img") },
+		"Run":            func() error { _, err := c.Run(NewCommand("reboot")); return err },
+	}
+	for name, mutate := range mutations {
+		if err := mutate(); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s: got %v, want ErrReadOnly", name, err)
+		}
+	}
+}
+
+func TestTokenAuthentication(t *testing.T) {
+	logins := 0
+	bmc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/bmc/authenticate" {
+			logins++
+			fmt.Fprintf(w, `{"id":"session-%d"}`, logins)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer preset" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"response":[{"result":{"api":"1.1"}}]}`)
+	}))
+	defer bmc.Close()
+
+	newClient := func(token string) *Client {
+		c, err := NewClient(context.Background(), Config{
+			Host:   strings.TrimPrefix(bmc.URL, "http://"),
+			Scheme: SchemeHTTP,
+			Token:  token,
+			Retry:  RetryPolicy{MaxAttempts: 1},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	c := newClient("preset")
+	if _, err := c.Info(); err != nil {
+		t.Fatalf("Info with valid token: %v", err)
+	}
+
+	session, err := c.NewSession()
+	if err != nil || session != "session-1" {
+		t.Fatalf("NewSession = %q, %v; want session-1", session, err)
+	}
+	if _, err := c.Info(); err != nil {
+		t.Errorf("Info after NewSession: %v; the client's own token should be unchanged", err)
+	}
+
+	if _, err := newClient("expired").Info(); !errors.Is(err, ErrAuthentication) {
+		t.Errorf("Info with rejected token and no credentials: got %v, want ErrAuthentication", err)
+	}
+	if logins != 1 {
+		t.Errorf("got %d logins, want 1", logins)
+	}
+}
+
+func TestFlashNodeRetriesOnlyStart(t *testing.T) {
+	defer func(interval time.Duration) { flashPollInterval = interval }(flashPollInterval)
+	flashPollInterval = time.Millisecond
+
+	var starts, uploads, polls int
+	bmc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/bmc/authenticate":
+			fmt.Fprint(w, `{"id":"token"}`)
+		case r.URL.Path == "/api/bmc/upload/1":
+			uploads++
+			io.Copy(io.Discard, r.Body)
+		case r.URL.Query().Get("type") != "flash":
+			http.NotFound(w, r)
+		case r.URL.Query().Get("opt") == "set":
+			// The first announcement fails transiently and is retried
+			starts++
+			if starts == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, `{"handle":1}`)
+		default:
+			// Progress never comes back while the BMC is busy writing
+			polls++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer bmc.Close()
+
+	c, err := NewClient(context.Background(), Config{
+		Host:   strings.TrimPrefix(bmc.URL, "http://"),
+		Scheme: SchemeHTTP,
+		Retry:  RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := filepath.Join(t.TempDir(), "image.img")
+	if err := os.WriteFile(image, []byte("turingpi"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.FlashNode(1, &tpi.FlashOptions{ImagePath: image})
+	if err == nil || !strings.Contains(err.Error(), "checking flash progress") {
+		t.Fatalf("FlashNode = %v, want the progress error", err)
+	}
+	if starts != 2 || uploads != 1 || polls != flashMaxPollErrors {
+		t.Errorf("got %d announcements, %d uploads and %d polls; want 2, 1 and %d", starts, uploads, polls, flashMaxPollErrors)
+	}
+}
+
+func TestFlashNodeLocalNotRetriedAfterAccepted(t *testing.T) {
+	var updates int
+	bmc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/bmc/authenticate":
+			fmt.Fprint(w, `{"id":"token"}`)
+		case r.URL.Query().Get("type") == "update":
+			// The BMC starts flashing, but the response is lost
+			updates++
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			conn.Close()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer bmc.Close()
+
+	c, err := NewClient(context.Background(), Config{
+		Host:   strings.TrimPrefix(bmc.URL, "http://"),
+		Scheme: SchemeHTTP,
+		Retry:  RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.FlashNodeLocal(1, "/tmp/tpi-cache/image.img")
+	if err == nil || !IsTransient(err) {
+		t.Fatalf("FlashNodeLocal = %v, want the transient transport error", err)
+	}
+	if updates != 1 {
+		t.Errorf("BMC received %d flash requests, want 1", updates)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Command is a program and its arguments to run on the BMC. The BMC runs
+// commands through its shell, so every word is quoted when the command line
+// is built; arguments reach the program verbatim whatever they contain.
+type Command struct {
+	Name string
+	Args []string
+
+	// Idempotent marks a command that is safe to run again, so it is retried
+	// after transient SSH errors. Other commands may already have run when
+	// the session fails and are never retried.
+	Idempotent bool
+}
+
+// NewCommand returns a command that runs name with args.
+func NewCommand(name string, args ...string) Command {
+	return Command{Name: name, Args: args}
+}
+
+// retryable reports whether the command is run again after err.
+func (c Command) retryable(err error) bool {
+	return c.Idempotent && IsTransient(err)
+}
+
+// String returns the quoted command line sent to the BMC shell.
+func (c Command) String() string {
+	words := make([]string, 0, len(c.Args)+1)
+	words = append(words, ShellQuote(c.Name))
+	for _, arg := range c.Args {
+		words = append(words, ShellQuote(arg))
+	}
+	return strings.Join(words, " ")
+}
+
+// CommandResult is the outcome of a command that ran on the BMC.
+type CommandResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// CommandError is returned when a command exits with a non-zero status.
+type CommandError struct {
+	Command  string // Command line as sent to the BMC
+	ExitCode int
+	Stderr   string
+}
+
+func (e *CommandError) Error() string {
+	msg := fmt.Sprintf("command %q exited with status %d", e.Command, e.ExitCode)
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg += ": " + stderr
+	}
+	return msg
+}
+
+// ShellQuote quotes s as a single word for a POSIX shell. Words made only of
+// characters the shell never interprets are returned unchanged.
+func ShellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for _, r := range s {
+		if !isShellSafe(r) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func isShellSafe(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	default:
+		return strings.ContainsRune("@%+=:,./_-", r)
+	}
+}
+
+// Run runs a command on the BMC over SSH. Commands can change the BMC, so
+// they are refused in read-only mode.
+func (c *Client) Run(cmd Command) (*CommandResult, error) {
+	return c.RunContext(c.ctx, cmd)
+}
+
+// RunContext is Run with a context that aborts the session. The command may
+// still complete on the BMC.
+//
+// A command that exits with a non-zero status returns its result together
+// with a *CommandError; other errors mean the command did not run to
+// completion and return a nil result.
+func (c *Client) RunContext(ctx context.Context, cmd Command) (result *CommandResult, err error) {
+	ctx, span := startSpan(ctx, "client.Run")
+	defer func() { endSpan(span, err) }()
+
+	if err := c.checkWritable("Run"); err != nil {
+		return nil, err
+	}
+	if cmd.Name == "" {
+		return nil, fmt.Errorf("command name is required")
+	}
+	defer c.audit.begin(ctx, "Run", 0, map[string]interface{}{"command": cmd.String()}).end(&err)
+	return retryIf(ctx, c, "Run", cmd.retryable, func(ctx context.Context) (*CommandResult, error) {
+		result, err := c.run(ctx, cmd.String())
+		return result, cancelledError(ctx, err)
+	})
+}
+
+// run runs line in a single SSH session attempt.
+func (c *Client) run(ctx context.Context, line string) (*CommandResult, error) {
+	conn, err := c.sshConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to establish SSH connection: %w", err)
+	}
+
+	session, err := conn.NewSession()
+	if err != nil {
+		c.discardSSH(ctx, conn)
+		return nil, fmt.Errorf("failed to create SSH session: %w", err)
+	}
+	defer session.Close()
+	defer closeOnCancel(ctx, session)()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+
+	err = session.Run(line)
+	result := &CommandResult{Stdout: stdout.String(), Stderr: stderr.String()}
+
+	var exitErr *ssh.ExitError
+	switch {
+	case err == nil:
+		return result, nil
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitStatus()
+		return result, &CommandError{Command: line, ExitCode: result.ExitCode, Stderr: result.Stderr}
+	default:
+		return nil, fmt.Errorf("command execution failed: %w", err)
+	}
+}
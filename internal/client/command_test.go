@@ -0,0 +1,59 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"":                    "''",
+		"/tmp/tpi-cache":      "/tmp/tpi-cache",
+		"a b":                 "'a b'",
+		"it's":                `'it'\''s'`,
+		"$(reboot)":           "'$(reboot)'",
+		"x; rm -rf /":         "'x; rm -rf /'",
+		"*.img":               "'*.img'",
+		"line\nbreak":         "'line\nbreak'",
+		"name=value,user@bmc": "name=value,user@bmc",
+	}
+	for in, want := range tests {
+		if got := ShellQuote(in); got != want {
+			t.Errorf("ShellQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestCommandString(t *testing.T) {
+	cmd := NewCommand("mkdir", "-p", "--", "/tmp/tpi cache/$HOME")
+	if got, want := cmd.String(), `mkdir -p -- '/tmp/tpi cache/$HOME'`; got != want {
+		t.Errorf("String() = %s, want %s", got, want)
+	}
+}
+
+func TestCommandError(t *testing.T) {
+	err := &CommandError{Command: "cat /missing", ExitCode: 1, Stderr: "cat: can't open '/missing'\n"}
+	if got, want := err.Error(), `command "cat /missing" exited with status 1: cat: can't open '/missing'`; got != want {
+		t.Errorf("Error() = %s, want %s", got, want)
+	}
+}
+
+func TestCommandRetryable(t *testing.T) {
+	transient := fmt.Errorf("run: %w", io.EOF)
+	idempotent := NewCommand("mkdir", "-p", "--", "/tmp/tpi-cache")
+	idempotent.Idempotent = true
+
+	if NewCommand("reboot").retryable(transient) {
+		t.Error("a command not marked idempotent is retried")
+	}
+	if !idempotent.retryable(transient) {
+		t.Error("an idempotent command is not retried after a transient error")
+	}
+	if idempotent.retryable(&CommandError{Command: "mkdir", ExitCode: 1}) {
+		t.Error("an idempotent command is retried after it failed")
+	}
+}
@@ -4,247 +4,534 @@
 package client
 
 import (
-	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
 	"compress/gzip"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"io"
-	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
 	"github.com/ulikunitz/xz"
 )
 
 // DownloadResult contains the result of a download operation.
 type DownloadResult struct {
-	Path   string // Path to the downloaded (and decompressed) file
-	SHA256 string // SHA256 hash of the final decompressed file
+	Path             string // Path to the downloaded (and decompressed) file
+	SHA256           string // SHA256 hash of the final decompressed file
+	CompressedSHA256 string // SHA256 hash of the bytes as downloaded; equal to SHA256 when not compressed
+	CompressedPath   string // Path to the compressed download, when kept with KeepCompressed
 }
 
 // DownloadOptions configures the download behavior.
 type DownloadOptions struct {
 	ExpectedSHA256 string // Optional: expected SHA256 for verification
 	DestDir        string // Destination directory (default: temp dir)
+	KeepCompressed bool   // Keep the compressed download next to the image
+	ResumeDir      string // Optional: where interrupted downloads are kept to be resumed
+	Connections    int    // Concurrent ranged requests; 0 or 1 uses a single stream
+	Compression    string // Optional: one of Compressions or CompressionNone, overriding detection
+	ArchiveMember  string // Optional: name or glob of the archive member holding the image
+
+	// ExpectedCompressedSHA256 is the SHA256 of the download or file itself,
+	// as published in checksum files, rather than of the image in it
+	ExpectedCompressedSHA256 string
 }
 
+// CompressionNone marks an image as not compressed, whatever it looks like.
+const CompressionNone = "none"
+
+// Compressions lists the supported compression formats. "tar" is an
+// uncompressed tar archive; compressed ones are recognized once decompressed.
+var Compressions = []string{"xz", "gz", "zip", "zst", "bz2", "lz4", "tar"}
+
 // DownloadImage downloads an image from a URL, automatically decompressing if needed.
-// Supports .xz, .gz, and .zip compression.
-func DownloadImage(ctx context.Context, url string, opts *DownloadOptions) (*DownloadResult, error) {
+// Supports xz, gzip, zip, zstd, bzip2 and lz4 compression, recognized by the
+// magic number the download starts with. The URL's file name and the
+// Content-Type are only used when the first bytes are not conclusive, and
+// opts.Compression overrides both. Zip and tar archives, including .tar.gz and
+// .tar.xz, are replaced by the member opts.ArchiveMember selects.
+//
+// The response is decompressed and hashed as it arrives, so the image is
+// written to disk once and the compressed download is only saved when
+// KeepCompressed is set. Zip archives keep their index at the end, so they are
+// saved before the image is extracted.
+//
+// With a ResumeDir, the compressed bytes are also recorded there as they
+// arrive. If the download is interrupted, the next call for the same URL asks
+// the server for the rest with a Range request, validated against the ETag or
+// Last-Modified date recorded with the partial download so content that
+// changed in between is downloaded again from the start.
+//
+// With Connections above 1, images larger than one chunk are fetched as that
+// many concurrent Range requests when the server accepts them, and fed to the
+// pipeline in order, so the digests and files are the same as for a single
+// stream.
+func DownloadImage(ctx context.Context, url string, opts *DownloadOptions) (_ *DownloadResult, err error) {
 	if opts == nil {
 		opts = &DownloadOptions{}
 	}
 
+	ctx, span := startSpan(ctx, "download.image", attrURL.String(redactURL(url)))
+	defer func() { endSpan(span, err) }()
+
 	// Create destination directory
 	destDir := opts.DestDir
 	if destDir == "" {
-		var err error
 		destDir, err = os.MkdirTemp("", "turingpi-download-*")
 		if err != nil {
 			return nil, fmt.Errorf("failed to create temp directory: %w", err)
 		}
 	}
 
-	// Download file
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	result, err := fetch(ctx, url, destDir, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to download: %w", err)
+	if err := verifyResult(result, opts); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return result, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("download failed with status: %d", resp.StatusCode)
+// verifyResult checks result against the digests expected by opts, removing
+// its files when they do not match.
+func verifyResult(result *DownloadResult, opts *DownloadOptions) error {
+	var err error
+	switch {
+	case opts.ExpectedCompressedSHA256 != "" && result.CompressedSHA256 != opts.ExpectedCompressedSHA256:
+		err = fmt.Errorf("%w: expected SHA256 %s for the downloaded file, got %s", ErrChecksumMismatch, opts.ExpectedCompressedSHA256, result.CompressedSHA256)
+	case opts.ExpectedSHA256 != "" && result.SHA256 != opts.ExpectedSHA256:
+		err = fmt.Errorf("%w: expected SHA256 %s, got %s", ErrChecksumMismatch, opts.ExpectedSHA256, result.SHA256)
 	}
-
-	// Determine filename and compression type
-	filename := filepath.Base(url)
-	compression := detectCompression(url, resp.Header.Get("Content-Type"))
-
-	// Save the downloaded file
-	downloadPath := filepath.Join(destDir, filename)
-	downloadFile, err := os.Create(downloadPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create download file: %w", err)
+		os.Remove(result.Path)
+		if result.CompressedPath != "" {
+			os.Remove(result.CompressedPath)
+		}
 	}
+	return err
+}
 
-	_, err = io.Copy(downloadFile, resp.Body)
-	downloadFile.Close()
+// fetch downloads url into destDir, decompressing it on the way. With a
+// resume directory, the download continues from a partial one recorded there
+// and is recorded in turn. With more than one connection, large downloads
+// from servers that accept ranges are fetched in parallel chunks.
+func fetch(ctx context.Context, url, destDir string, opts *DownloadOptions) (_ *DownloadResult, err error) {
+	ctx, span := startSpan(ctx, "download.fetch")
+	defer func() { endSpan(span, err) }()
+
+	resp, part, err := openDownload(ctx, url, opts.ResumeDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to save download: %w", err)
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	// Decompress if needed
-	finalPath := downloadPath
-	if compression != "" {
-		finalPath, err = decompress(downloadPath, compression)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decompress: %w", err)
+	var body io.Reader = resp.Body
+	if opts.Connections > 1 && parallelizable(resp) {
+		var start int64
+		if part != nil {
+			start = part.offset
 		}
-		// Remove the compressed file
-		os.Remove(downloadPath)
+		span.SetAttributes(attrConnections.Int(opts.Connections))
+		chunks := newChunkedReader(ctx, url, contentValidator(resp), resp.Body, start, start+resp.ContentLength, opts.Connections)
+		defer chunks.Close()
+		body = chunks
 	}
-
-	// Calculate SHA256
-	sha256Hash, err := calculateSHA256(finalPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to calculate SHA256: %w", err)
+	if part != nil {
+		span.SetAttributes(attrResumeOffset.Int64(part.offset))
+		body = part.reader(body)
+		defer part.finish(&err)
 	}
 
-	// Verify SHA256 if expected
-	if opts.ExpectedSHA256 != "" && sha256Hash != opts.ExpectedSHA256 {
-		os.Remove(finalPath)
-		return nil, fmt.Errorf("SHA256 mismatch: expected %s, got %s", opts.ExpectedSHA256, sha256Hash)
+	// Name the file after the URL the image came from, after redirects and
+	// without the query string
+	finalURL := resp.Request.URL
+	filename := filepath.Base(finalURL.Path)
+	if filename == "/" || filename == "." {
+		filename = "image"
 	}
 
-	return &DownloadResult{
-		Path:   finalPath,
-		SHA256: sha256Hash,
-	}, nil
-}
+	body, head := peekHead(body)
+	compression := chooseCompression(opts.Compression, head, resp.Header.Get("Content-Type"), finalURL.Path, url)
+	span.SetAttributes(attrCompression.String(compression))
 
-// detectCompression determines the compression type from URL or content type.
-func detectCompression(url, contentType string) string {
-	url = strings.ToLower(url)
+	downloadPath := filepath.Join(destDir, filename)
+	imagePath := decompressedPath(downloadPath, compression)
 
-	if strings.HasSuffix(url, ".xz") {
-		return "xz"
+	keepPath := ""
+	if opts.KeepCompressed && compression != "" && compression != "zip" {
+		keepPath = downloadPath
 	}
-	if strings.HasSuffix(url, ".gz") || strings.HasSuffix(url, ".gzip") {
-		return "gz"
+	if part != nil {
+		// The part file already holds the compressed download
+		part.keepAs, keepPath = keepPath, ""
 	}
-	if strings.HasSuffix(url, ".zip") {
-		return "zip"
+
+	if compression != "zip" {
+		result, err := streamImage(ctx, body, compression, imagePath, keepPath, opts.ArchiveMember)
+		if err == nil && part != nil && part.keepAs != "" {
+			result.CompressedPath = part.keepAs
+		}
+		return result, err
 	}
 
-	// Check content type
-	contentType = strings.ToLower(contentType)
-	if strings.Contains(contentType, "xz") || strings.Contains(contentType, "x-xz") {
-		return "xz"
+	archive, err := streamImage(ctx, body, "", downloadPath, "", "")
+	if err != nil {
+		return nil, err
 	}
-	if strings.Contains(contentType, "gzip") {
-		return "gz"
+	sum, err := extractZip(ctx, downloadPath, imagePath, opts.ArchiveMember)
+	if err != nil || !opts.KeepCompressed {
+		os.Remove(downloadPath)
 	}
-	if strings.Contains(contentType, "zip") {
-		return "zip"
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress: %w", spaceError(err))
 	}
 
-	return ""
+	result := &DownloadResult{
+		Path:             imagePath,
+		SHA256:           sum,
+		CompressedSHA256: archive.SHA256,
+	}
+	if opts.KeepCompressed {
+		result.CompressedPath = downloadPath
+	}
+	return result, nil
 }
 
-// decompress decompresses a file based on the compression type.
-func decompress(path, compression string) (string, error) {
-	outputPath := strings.TrimSuffix(path, "."+compression)
-	if outputPath == path {
-		outputPath = path + ".decompressed"
+// DecompressImage decompresses the local image at path into opts.DestDir,
+// hashing it on the way, and verifies the digests expected by opts. The
+// compression is detected as for DownloadImage, from the first bytes of the
+// file and then its name, unless opts.Compression is set.
+func DecompressImage(ctx context.Context, path string, opts *DownloadOptions) (_ *DownloadResult, err error) {
+	if opts == nil {
+		opts = &DownloadOptions{}
 	}
 
-	switch compression {
-	case "xz":
-		return decompressXZ(path, outputPath)
-	case "gz":
-		return decompressGzip(path, outputPath)
-	case "zip":
-		return decompressZip(path, outputPath)
-	default:
-		return "", fmt.Errorf("unsupported compression: %s", compression)
-	}
-}
+	ctx, span := startSpan(ctx, "image.decompress")
+	defer func() { endSpan(span, err) }()
 
-// decompressXZ decompresses an XZ file.
-func decompressXZ(src, dst string) (string, error) {
-	srcFile, err := os.Open(src)
+	file, err := os.Open(path)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer srcFile.Close()
+	defer file.Close()
 
-	reader, err := xz.NewReader(srcFile)
-	if err != nil {
-		return "", fmt.Errorf("failed to create xz reader: %w", err)
+	body, head := peekHead(file)
+	compression := chooseCompression(opts.Compression, head, "", path)
+	span.SetAttributes(attrCompression.String(compression))
+	if compression == "" {
+		return nil, fmt.Errorf("%s is not a compressed image", path)
 	}
 
-	dstFile, err := os.Create(dst)
-	if err != nil {
-		return "", err
+	destDir := opts.DestDir
+	if destDir == "" {
+		destDir, err = os.MkdirTemp("", "turingpi-decompress-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp directory: %w", err)
+		}
 	}
-	defer dstFile.Close()
+	imagePath := decompressedPath(filepath.Join(destDir, filepath.Base(path)), compression)
 
-	_, err = io.Copy(dstFile, reader)
-	if err != nil {
-		return "", fmt.Errorf("failed to decompress xz: %w", err)
+	var result *DownloadResult
+	if compression == "zip" {
+		compressedSHA256, err := calculateSHA256(path)
+		if err != nil {
+			return nil, err
+		}
+		sum, err := extractZip(ctx, path, imagePath, opts.ArchiveMember)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress: %w", spaceError(err))
+		}
+		result = &DownloadResult{Path: imagePath, SHA256: sum, CompressedSHA256: compressedSHA256}
+	} else {
+		result, err = streamImage(ctx, body, compression, imagePath, "", opts.ArchiveMember)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return dst, nil
+	if err := verifyResult(result, opts); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
-// decompressGzip decompresses a gzip file.
-func decompressGzip(src, dst string) (string, error) {
-	srcFile, err := os.Open(src)
+// DetectFileCompression returns the compression of the local file at path,
+// from its first bytes and then its name, or "" when it is not compressed.
+func DetectFileCompression(path string) (string, error) {
+	file, err := os.Open(path)
 	if err != nil {
 		return "", err
 	}
-	defer srcFile.Close()
+	defer file.Close()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	return chooseCompression("", head[:n], "", path), nil
+}
 
-	reader, err := gzip.NewReader(srcFile)
+// streamImage writes body, compressed with compression ("" for none), to dst
+// through the decompressor, hashing the compressed and the decompressed bytes
+// on the way. A tar archive, plain or compressed, is replaced by its member
+// chosen for pattern. The compressed bytes are also saved to keepPath unless
+// it is empty. Byte counts are recorded on the span in ctx.
+func streamImage(ctx context.Context, body io.Reader, compression, dst, keepPath, pattern string) (_ *DownloadResult, err error) {
+	compressedHash := sha256.New()
+	var compressedBytes byteCounter
+	sinks := []io.Writer{compressedHash, &compressedBytes}
+
+	if keepPath != "" {
+		keep, err := os.Create(keepPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create download file: %w", err)
+		}
+		defer func() {
+			if closeErr := keep.Close(); err == nil && closeErr != nil {
+				err = fmt.Errorf("failed to save download: %w", spaceError(closeErr))
+			}
+			if err != nil {
+				os.Remove(keepPath)
+			}
+		}()
+		sinks = append(sinks, keep)
+	}
+	compressed := io.TeeReader(body, io.MultiWriter(sinks...))
+
+	reader, err := newDecompressor(bufio.NewReaderSize(compressed, 1<<20), compression)
 	if err != nil {
-		return "", fmt.Errorf("failed to create gzip reader: %w", err)
+		return nil, err
 	}
 	defer reader.Close()
 
-	dstFile, err := os.Create(dst)
+	// Tar archives, compressed or not, are recognized once decompressed
+	var sum string
+	var written int64
+	decompressed, head := peekHead(reader)
+	if compression != "" && isTar(head) {
+		var member string
+		member, sum, written, err = extractTar(decompressed, dst, pattern)
+		setSpanAttributes(ctx, attrArchiveMember.String(member))
+	} else {
+		sum, written, err = writeFile(dst, decompressed)
+	}
+	if err == nil {
+		// Hash anything the decompressor left unread, so the compressed
+		// digest covers the whole download
+		_, err = io.Copy(io.Discard, compressed)
+	}
+	setSpanAttributes(ctx, attrBytes.Int64(int64(compressedBytes)), attrBytesOut.Int64(written))
 	if err != nil {
-		return "", err
+		os.Remove(dst)
+		return nil, fmt.Errorf("failed to write image: %w", spaceError(err))
 	}
-	defer dstFile.Close()
 
-	_, err = io.Copy(dstFile, reader)
-	if err != nil {
-		return "", fmt.Errorf("failed to decompress gzip: %w", err)
+	result := &DownloadResult{
+		Path:             dst,
+		SHA256:           sum,
+		CompressedSHA256: hex.EncodeToString(compressedHash.Sum(nil)),
+		CompressedPath:   keepPath,
 	}
+	return result, nil
+}
+
+// byteCounter counts the bytes written to it.
+type byteCounter int64
 
-	return dst, nil
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
 }
 
-// decompressZip extracts the first file from a zip archive.
-func decompressZip(src, dst string) (string, error) {
-	reader, err := zip.OpenReader(src)
-	if err != nil {
-		return "", fmt.Errorf("failed to open zip: %w", err)
+// sniffLen is how many leading bytes are needed to recognize a format: one
+// tar block.
+const sniffLen = 512
+
+// compressionMagic lists the magic numbers that start each format.
+var compressionMagic = []struct {
+	magic       []byte
+	compression string
+}{
+	{[]byte{0xFD, '7', 'z', 'X', 'Z', 0x00}, "xz"},
+	{[]byte{0x1F, 0x8B}, "gz"},
+	{[]byte{'P', 'K', 0x03, 0x04}, "zip"},
+	{[]byte{'P', 'K', 0x05, 0x06}, "zip"}, // Empty archive
+	{[]byte{0x28, 0xB5, 0x2F, 0xFD}, "zst"},
+	{[]byte{'B', 'Z', 'h'}, "bz2"},
+	{[]byte{0x04, 0x22, 0x4D, 0x18}, "lz4"},
+}
+
+// sniffCompression returns the compression whose magic number head starts
+// with, or "" when it matches none.
+func sniffCompression(head []byte) string {
+	for _, m := range compressionMagic {
+		if !bytes.HasPrefix(head, m.magic) {
+			continue
+		}
+		// "BZh" is followed by the block size, '1' to '9'
+		if m.compression == "bz2" && (len(head) < 4 || head[3] < '1' || head[3] > '9') {
+			continue
+		}
+		return m.compression
 	}
-	defer reader.Close()
+	if isTar(head) {
+		return "tar"
+	}
+	return ""
+}
 
-	if len(reader.File) == 0 {
-		return "", fmt.Errorf("zip archive is empty")
+// peekHead returns a reader for the whole of r and the first bytes it will
+// return, for sniffing.
+func peekHead(r io.Reader) (io.Reader, []byte) {
+	buffered := bufio.NewReaderSize(r, 1<<20)
+	head, _ := buffered.Peek(sniffLen)
+	return buffered, head
+}
+
+// chooseCompression picks the compression of an image: explicit when set,
+// otherwise what its first bytes show, otherwise what the first of names or
+// the content type suggests. CompressionNone and "" mean uncompressed.
+func chooseCompression(explicit string, head []byte, contentType string, names ...string) string {
+	switch explicit {
+	case CompressionNone:
+		return ""
+	case "":
+	default:
+		return explicit
 	}
 
-	// Extract the first file (assuming single-file archive for images)
-	file := reader.File[0]
-	srcFile, err := file.Open()
-	if err != nil {
-		return "", fmt.Errorf("failed to open file in zip: %w", err)
+	if compression := sniffCompression(head); compression != "" {
+		return compression
+	}
+	for _, name := range names {
+		if compression := DetectCompression(name); compression != "" {
+			return compression
+		}
 	}
-	defer srcFile.Close()
+	return detectCompression("", contentType)
+}
 
-	dstFile, err := os.Create(dst)
-	if err != nil {
-		return "", err
+// detectCompression determines the compression type from URL or content type.
+func detectCompression(url, contentType string) string {
+	url = strings.ToLower(url)
+
+	if strings.HasSuffix(url, ".xz") {
+		return "xz"
+	}
+	if strings.HasSuffix(url, ".gz") || strings.HasSuffix(url, ".gzip") || strings.HasSuffix(url, ".tgz") {
+		return "gz"
+	}
+	if strings.HasSuffix(url, ".txz") {
+		return "xz"
+	}
+	if strings.HasSuffix(url, ".tar") {
+		return "tar"
+	}
+	if strings.HasSuffix(url, ".zip") {
+		return "zip"
+	}
+	if strings.HasSuffix(url, ".zst") || strings.HasSuffix(url, ".zstd") {
+		return "zst"
+	}
+	if strings.HasSuffix(url, ".bz2") {
+		return "bz2"
+	}
+	if strings.HasSuffix(url, ".lz4") {
+		return "lz4"
 	}
-	defer dstFile.Close()
 
-	_, err = io.Copy(dstFile, srcFile)
-	if err != nil {
-		return "", fmt.Errorf("failed to extract from zip: %w", err)
+	// Check content type
+	contentType = strings.ToLower(contentType)
+	if strings.Contains(contentType, "xz") || strings.Contains(contentType, "x-xz") {
+		return "xz"
+	}
+	if strings.Contains(contentType, "gzip") {
+		return "gz"
+	}
+	if strings.Contains(contentType, "bzip2") {
+		return "bz2"
+	}
+	if strings.Contains(contentType, "zip") {
+		return "zip"
+	}
+	if strings.Contains(contentType, "zstd") {
+		return "zst"
+	}
+	if strings.Contains(contentType, "lz4") {
+		return "lz4"
+	}
+
+	return ""
+}
+
+// DetectCompression returns the compression implied by an image URL or file
+// name: "xz", "gz", "zip", "zst", "bz2", "lz4", "tar", or "" when it does not
+// look compressed. Query strings and fragments are ignored. Downloads and local
+// images are recognized by their first bytes before their names.
+func DetectCompression(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Path != "" {
+		rawURL = u.Path
+	}
+	return detectCompression(rawURL, "")
+}
+
+// decompressedPath returns where the image downloaded to path is written.
+func decompressedPath(path, compression string) string {
+	if compression == "" {
+		return path
 	}
+	outputPath := strings.TrimSuffix(path, "."+compression)
+	if outputPath == path {
+		return path + ".decompressed"
+	}
+	// The image extracted from a compressed tar is not a tar
+	return strings.TrimSuffix(outputPath, ".tar")
+}
 
-	return dst, nil
+// newDecompressor returns a reader decompressing r.
+func newDecompressor(r io.Reader, compression string) (io.ReadCloser, error) {
+	switch compression {
+	case "", "tar":
+		return io.NopCloser(r), nil
+	case "xz":
+		reader, err := xz.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create xz reader: %w", err)
+		}
+		return io.NopCloser(reader), nil
+	case "gz":
+		reader, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+		}
+		return reader, nil
+	case "zst":
+		reader, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd reader: %w", err)
+		}
+		return reader.IOReadCloser(), nil
+	case "bz2":
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	case "lz4":
+		// lz4.Reader's WriteTo cannot continue after Read, which
+		// sniffing the decompressed head does first
+		return io.NopCloser(struct{ io.Reader }{lz4.NewReader(r)}), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", compression)
+	}
 }
 
 // calculateSHA256 calculates the SHA256 hash of a file.
@@ -267,3 +554,15 @@ func calculateSHA256(path string) (string, error) {
 func CalculateFileSHA256(path string) (string, error) {
 	return calculateSHA256(path)
 }
+
+// CalculateFileSHA256Context is CalculateFileSHA256 traced as a child of the
+// span in ctx.
+func CalculateFileSHA256Context(ctx context.Context, path string) (_ string, err error) {
+	_, span := startSpan(ctx, "sha256")
+	defer func() { endSpan(span, err) }()
+
+	if stat, err := os.Stat(path); err == nil {
+		span.SetAttributes(attrBytes.Int64(stat.Size()))
+	}
+	return calculateSHA256(path)
+}
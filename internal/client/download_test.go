@@ -0,0 +1,667 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ulikunitz/xz"
+)
+
+// testImage is the decompressed content served by the download tests.
+var testImage = bytes.Repeat([]byte("turingpi image "), 4096)
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func compressTestImage(t *testing.T, compression string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	switch compression {
+	case "":
+		buf.Write(testImage)
+	case "gz":
+		w := gzip.NewWriter(&buf)
+		w.Write(testImage)
+		w.Close()
+	case "xz":
+		w, err := xz.NewWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(testImage)
+		w.Close()
+	case "zip":
+		w := zip.NewWriter(&buf)
+		f, err := w.Create("image.img")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write(testImage)
+		w.Close()
+	}
+	return buf.Bytes()
+}
+
+// serveBytes serves data at every path.
+func serveBytes(t *testing.T, data []byte) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDownloadImage(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		compression string
+	}{
+		{"uncompressed", "image.img", ""},
+		{"gzip", "image.img.gz", "gz"},
+		{"xz", "image.img.xz", "xz"},
+		{"zip", "image.zip", "zip"},
+	}
+
+	for _, tt := range tests {
+		for _, keep := range []bool{false, true} {
+			name := tt.name
+			if keep {
+				name += " keep compressed"
+			}
+			t.Run(name, func(t *testing.T) {
+				compressed := compressTestImage(t, tt.compression)
+				server := serveBytes(t, compressed)
+				dir := t.TempDir()
+
+				result, err := DownloadImage(context.Background(), server.URL+"/"+tt.file, &DownloadOptions{
+					DestDir:        dir,
+					ExpectedSHA256: sha256Hex(testImage),
+					KeepCompressed: keep,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if result.SHA256 != sha256Hex(testImage) {
+					t.Errorf("SHA256 = %s, want %s", result.SHA256, sha256Hex(testImage))
+				}
+				if result.CompressedSHA256 != sha256Hex(compressed) {
+					t.Errorf("CompressedSHA256 = %s, want %s", result.CompressedSHA256, sha256Hex(compressed))
+				}
+				data, err := os.ReadFile(result.Path)
+				if err != nil || !bytes.Equal(data, testImage) {
+					t.Errorf("image at %s does not match (err %v)", result.Path, err)
+				}
+
+				// Only the image is left behind unless the compressed
+				// download was asked for
+				entries, _ := os.ReadDir(dir)
+				wantFiles := 1
+				if keep && tt.compression != "" {
+					wantFiles = 2
+					kept, err := os.ReadFile(result.CompressedPath)
+					if err != nil || !bytes.Equal(kept, compressed) {
+						t.Errorf("compressed download at %q does not match (err %v)", result.CompressedPath, err)
+					}
+				} else if result.CompressedPath != "" {
+					t.Errorf("CompressedPath = %q, want none", result.CompressedPath)
+				}
+				if len(entries) != wantFiles {
+					t.Errorf("%d files in %s, want %d", len(entries), dir, wantFiles)
+				}
+			})
+		}
+	}
+}
+
+func TestDownloadImageChecksumMismatch(t *testing.T) {
+	server := serveBytes(t, compressTestImage(t, "gz"))
+	dir := t.TempDir()
+
+	_, err := DownloadImage(context.Background(), server.URL+"/image.img.gz", &DownloadOptions{
+		DestDir:        dir,
+		ExpectedSHA256: sha256Hex([]byte("something else")),
+		KeepCompressed: true,
+	})
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("err = %v, want ErrChecksumMismatch", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("files left behind after a checksum mismatch: %v", entries)
+	}
+}
+
+func TestDownloadImageCorrupt(t *testing.T) {
+	compressed := compressTestImage(t, "gz")
+	server := serveBytes(t, compressed[:len(compressed)/2])
+	dir := t.TempDir()
+
+	if _, err := DownloadImage(context.Background(), server.URL+"/image.img.gz", &DownloadOptions{DestDir: dir}); err == nil {
+		t.Fatal("truncated download succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "image.img")); !os.IsNotExist(err) {
+		t.Errorf("partial image left behind (stat err %v)", err)
+	}
+}
+
+// flakyServer serves data with the given ETag, dropping the connection
+// halfway through the first response.
+type flakyServer struct {
+	data   []byte
+	etag   string
+	ranges []string // Range header of each request
+}
+
+func (s *flakyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.ranges = append(s.ranges, r.Header.Get("Range"))
+	w.Header().Set("ETag", s.etag)
+	if len(s.ranges) == 1 {
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Content-Length", strconv.Itoa(len(s.data)))
+		w.Write(s.data[:len(s.data)/2])
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(s.data))
+}
+
+func TestDownloadImageResume(t *testing.T) {
+	compressed := compressTestImage(t, "gz")
+	tests := []struct {
+		name      string
+		nextETag  string // ETag once the connection is back
+		wantRange string
+	}{
+		{"same content", `"v1"`, fmt.Sprintf("bytes=%d-", len(compressed)/2)},
+		{"changed content", `"v2"`, fmt.Sprintf("bytes=%d-", len(compressed)/2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaky := &flakyServer{data: compressed, etag: `"v1"`}
+			server := httptest.NewServer(flaky)
+			defer server.Close()
+			resumeDir := t.TempDir()
+			opts := &DownloadOptions{DestDir: t.TempDir(), ResumeDir: resumeDir}
+
+			if _, err := DownloadImage(context.Background(), server.URL+"/image.img.gz", opts); err == nil {
+				t.Fatal("interrupted download succeeded")
+			}
+			key := partialKey(server.URL+"/image.img.gz", `"v1"`)
+			if stat, err := os.Stat(filepath.Join(resumeDir, key+".part")); err != nil || stat.Size() != int64(len(compressed)/2) {
+				t.Fatalf("partial download not kept: %v", err)
+			}
+
+			flaky.etag = tt.nextETag
+			result, err := DownloadImage(context.Background(), server.URL+"/image.img.gz", opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if flaky.ranges[1] != tt.wantRange {
+				t.Errorf("Range = %q, want %q", flaky.ranges[1], tt.wantRange)
+			}
+			if result.SHA256 != sha256Hex(testImage) || result.CompressedSHA256 != sha256Hex(compressed) {
+				t.Errorf("digests = %s, %s; want %s, %s", result.SHA256, result.CompressedSHA256, sha256Hex(testImage), sha256Hex(compressed))
+			}
+			if entries, _ := os.ReadDir(resumeDir); len(entries) != 0 {
+				t.Errorf("partial download left behind: %v", entries)
+			}
+		})
+	}
+}
+
+// buildServer serves the current build behind one URL, dropping the
+// connection halfway through while drop is set.
+type buildServer struct {
+	data   []byte
+	etag   string
+	drop   bool
+	ranges []string // Range header of each request
+}
+
+func (s *buildServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.ranges = append(s.ranges, r.Header.Get("Range"))
+	w.Header().Set("ETag", s.etag)
+	w.Header().Set("Accept-Ranges", "bytes")
+	if s.drop {
+		w.Header().Set("Content-Length", strconv.Itoa(len(s.data)))
+		w.Write(s.data[:len(s.data)/2])
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(s.data))
+}
+
+func TestDownloadImageResumeKeyedByContent(t *testing.T) {
+	buildA := bytes.Repeat([]byte("nightly A "), 10000)
+	buildB := bytes.Repeat([]byte("nightly B "), 12000)
+	builds := &buildServer{data: buildA, etag: `"a"`, drop: true}
+	server := httptest.NewServer(builds)
+	defer server.Close()
+	url := server.URL + "/nightly.img"
+	resumeDir := t.TempDir()
+	opts := &DownloadOptions{DestDir: t.TempDir(), ResumeDir: resumeDir}
+
+	// Both builds are interrupted; the second keeps the first's bytes
+	if _, err := DownloadImage(context.Background(), url, opts); err == nil {
+		t.Fatal("interrupted download succeeded")
+	}
+	builds.data, builds.etag = buildB, `"b"`
+	if _, err := DownloadImage(context.Background(), url, opts); err == nil {
+		t.Fatal("interrupted download succeeded")
+	}
+	for _, key := range []string{partialKey(url, `"a"`), partialKey(url, `"b"`)} {
+		if _, err := os.Stat(filepath.Join(resumeDir, key+".part")); err != nil {
+			t.Fatalf("partial download missing: %v", err)
+		}
+	}
+
+	// The URL serves build A again, which resumes where it stopped
+	builds.data, builds.etag, builds.drop = buildA, `"a"`, false
+	result, err := DownloadImage(context.Background(), url, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SHA256 != sha256Hex(buildA) {
+		t.Errorf("SHA256 = %s, want %s", result.SHA256, sha256Hex(buildA))
+	}
+	if got, want := builds.ranges[len(builds.ranges)-1], fmt.Sprintf("bytes=%d-", len(buildA)/2); got != want {
+		t.Errorf("Range = %q, want %q", got, want)
+	}
+	if entries, _ := os.ReadDir(resumeDir); len(entries) != 0 {
+		t.Errorf("partial downloads left behind: %v", entries)
+	}
+}
+
+func TestDownloadImageResumeKeepCompressed(t *testing.T) {
+	compressed := compressTestImage(t, "xz")
+	flaky := &flakyServer{data: compressed, etag: `"v1"`}
+	server := httptest.NewServer(flaky)
+	defer server.Close()
+	opts := &DownloadOptions{DestDir: t.TempDir(), ResumeDir: t.TempDir(), KeepCompressed: true}
+
+	DownloadImage(context.Background(), server.URL+"/image.img.xz", opts)
+	result, err := DownloadImage(context.Background(), server.URL+"/image.img.xz", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, err := os.ReadFile(result.CompressedPath)
+	if err != nil || !bytes.Equal(kept, compressed) {
+		t.Errorf("compressed download at %q does not match (err %v)", result.CompressedPath, err)
+	}
+}
+
+func TestDownloadImageCorruptPartialDiscarded(t *testing.T) {
+	compressed := compressTestImage(t, "gz")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(compressed[:len(compressed)-8]))
+	}))
+	defer server.Close()
+	resumeDir := t.TempDir()
+
+	// The server delivered everything it has; resuming would fail again
+	if _, err := DownloadImage(context.Background(), server.URL+"/image.img.gz", &DownloadOptions{DestDir: t.TempDir(), ResumeDir: resumeDir}); err == nil {
+		t.Fatal("truncated gzip succeeded")
+	}
+	if entries, _ := os.ReadDir(resumeDir); len(entries) != 0 {
+		t.Errorf("corrupt partial download kept: %v", entries)
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		value       string
+		start, size int64
+		ok          bool
+	}{
+		{"bytes 100-199/200", 100, 200, true},
+		{"bytes 0-99/*", 0, -1, true},
+		{"bytes */200", 0, 0, false},
+		{"items 0-1/2", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tt := range tests {
+		start, size, ok := parseContentRange(tt.value)
+		if start != tt.start || size != tt.size || ok != tt.ok {
+			t.Errorf("parseContentRange(%q) = %d, %d, %t; want %d, %d, %t", tt.value, start, size, ok, tt.start, tt.size, tt.ok)
+		}
+	}
+}
+
+// rangeServer serves data with ETag etag, counting the ranged requests.
+type rangeServer struct {
+	mu     sync.Mutex
+	data   []byte
+	etag   string
+	ranged int
+}
+
+func (s *rangeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	if r.Header.Get("Range") != "" {
+		s.ranged++
+	}
+	w.Header().Set("ETag", s.etag)
+	s.mu.Unlock()
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(s.data))
+}
+
+func TestDownloadImageParallel(t *testing.T) {
+	data := make([]byte, 3*downloadChunkSize+12345)
+	rand.New(rand.NewSource(1)).Read(data)
+	ranges := &rangeServer{data: data, etag: `"v1"`}
+	server := httptest.NewServer(ranges)
+	defer server.Close()
+
+	result, err := DownloadImage(context.Background(), server.URL+"/image.img", &DownloadOptions{
+		DestDir:     t.TempDir(),
+		Connections: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SHA256 != sha256Hex(data) {
+		t.Errorf("SHA256 = %s, want %s", result.SHA256, sha256Hex(data))
+	}
+	// The first chunk comes from the initial request
+	if ranges.ranged != 3 {
+		t.Errorf("%d ranged requests, want 3", ranges.ranged)
+	}
+}
+
+func TestDownloadImageParallelContentChanged(t *testing.T) {
+	data := make([]byte, 2*downloadChunkSize+1)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The image is replaced once the download has started
+		etag := `"v2"`
+		if requests.Add(1) == 1 {
+			etag = `"v1"`
+		}
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+
+	_, err := DownloadImage(context.Background(), server.URL+"/image.img", &DownloadOptions{
+		DestDir:     t.TempDir(),
+		Connections: 2,
+	})
+	if err == nil || !strings.Contains(err.Error(), "content may have changed") {
+		t.Errorf("err = %v, want a changed content error", err)
+	}
+}
+
+func TestDetectCompression(t *testing.T) {
+	tests := []struct {
+		url, contentType, want string
+	}{
+		{"https://example.com/image.img.xz", "", "xz"},
+		{"https://example.com/image.img.gz", "", "gz"},
+		{"https://example.com/image.zip", "", "zip"},
+		{"https://example.com/Armbian.img.zst", "", "zst"},
+		{"https://example.com/image.img.zstd", "", "zst"},
+		{"https://example.com/image.img.bz2", "", "bz2"},
+		{"https://example.com/image.img.lz4", "", "lz4"},
+		{"https://example.com/bundle.tar", "", "tar"},
+		{"https://example.com/bundle.tar.gz", "", "gz"},
+		{"https://example.com/bundle.tgz", "", "gz"},
+		{"https://example.com/bundle.tar.xz", "", "xz"},
+		{"https://example.com/bundle.txz", "", "xz"},
+		{"https://example.com/download", "application/zstd", "zst"},
+		{"https://example.com/download", "application/x-bzip2", "bz2"},
+		{"https://example.com/download", "application/x-lz4", "lz4"},
+		{"https://example.com/download", "application/zip", "zip"},
+		{"https://example.com/image.img", "application/octet-stream", ""},
+	}
+	for _, tt := range tests {
+		if got := detectCompression(tt.url, tt.contentType); got != tt.want {
+			t.Errorf("detectCompression(%q, %q) = %q, want %q", tt.url, tt.contentType, got, tt.want)
+		}
+	}
+}
+
+// fixtureCompressions are the formats with archives of testdata/image.img
+// made by the reference tools.
+var fixtureCompressions = []string{"zst", "bz2", "lz4"}
+
+func TestDownloadImageFixtures(t *testing.T) {
+	image, err := os.ReadFile("testdata/image.img")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, compression := range fixtureCompressions {
+		t.Run(compression, func(t *testing.T) {
+			compressed, err := os.ReadFile("testdata/image.img." + compression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := serveBytes(t, compressed)
+
+			result, err := DownloadImage(context.Background(), server.URL+"/image.img."+compression, &DownloadOptions{
+				DestDir:        t.TempDir(),
+				ExpectedSHA256: sha256Hex(image),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.CompressedSHA256 != sha256Hex(compressed) {
+				t.Errorf("CompressedSHA256 = %s, want %s", result.CompressedSHA256, sha256Hex(compressed))
+			}
+			if filepath.Base(result.Path) != "image.img" {
+				t.Errorf("image saved as %s, want image.img", filepath.Base(result.Path))
+			}
+		})
+	}
+}
+
+func TestDecompressImage(t *testing.T) {
+	image, err := os.ReadFile("testdata/image.img")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, compression := range fixtureCompressions {
+		t.Run(compression, func(t *testing.T) {
+			dir := t.TempDir()
+			result, err := DecompressImage(context.Background(), "testdata/image.img."+compression, &DownloadOptions{DestDir: dir})
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(result.Path)
+			if err != nil || !bytes.Equal(data, image) || result.SHA256 != sha256Hex(image) {
+				t.Errorf("decompressed image %s does not match (err %v)", result.Path, err)
+			}
+		})
+	}
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := DecompressImage(context.Background(), "testdata/image.img.zst", &DownloadOptions{
+			DestDir:        dir,
+			ExpectedSHA256: sha256Hex([]byte("something else")),
+		})
+		if !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("err = %v, want ErrChecksumMismatch", err)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("files left behind after a checksum mismatch: %v", entries)
+		}
+	})
+}
+
+func TestDownloadImageParallelResume(t *testing.T) {
+	data := make([]byte, 3*downloadChunkSize+12345)
+	rand.New(rand.NewSource(2)).Read(data)
+	var mu sync.Mutex
+	down := true // The connection drops after the first chunk
+	ranges := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		failing := down
+		if !down {
+			ranges[r.Header.Get("Range")] = true
+		}
+		mu.Unlock()
+
+		w.Header().Set("ETag", `"v1"`)
+		if failing && r.Header.Get("Range") != "" {
+			panic(http.ErrAbortHandler)
+		}
+		if failing {
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			w.Write(data[:downloadChunkSize+1])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+	opts := &DownloadOptions{DestDir: t.TempDir(), ResumeDir: t.TempDir(), Connections: 4}
+
+	if _, err := DownloadImage(context.Background(), server.URL+"/image.img", opts); err == nil {
+		t.Fatal("interrupted download succeeded")
+	}
+	mu.Lock()
+	down = false
+	mu.Unlock()
+
+	result, err := DownloadImage(context.Background(), server.URL+"/image.img", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SHA256 != sha256Hex(data) {
+		t.Errorf("SHA256 = %s, want %s", result.SHA256, sha256Hex(data))
+	}
+
+	// The first chunk was kept; the resumed request carries the second
+	mu.Lock()
+	defer mu.Unlock()
+	for _, want := range []string{
+		fmt.Sprintf("bytes=%d-", downloadChunkSize),
+		fmt.Sprintf("bytes=%d-%d", 2*downloadChunkSize, 3*downloadChunkSize-1),
+		fmt.Sprintf("bytes=%d-%d", 3*downloadChunkSize, len(data)-1),
+	} {
+		if !ranges[want] {
+			t.Errorf("no request for %s; got %v", want, ranges)
+		}
+	}
+}
+
+func TestSniffCompression(t *testing.T) {
+	tests := []struct {
+		name string
+		head []byte
+		want string
+	}{
+		{"xz", compressTestImage(t, "xz"), "xz"},
+		{"gzip", compressTestImage(t, "gz"), "gz"},
+		{"zip", compressTestImage(t, "zip"), "zip"},
+		{"raw", compressTestImage(t, ""), ""},
+		{"tar", buildArchive(t, "tar", bundleFiles), "tar"},
+		{"tar.gz", buildArchive(t, "tar.gz", bundleFiles), "gz"},
+		{"bzip2 without block size", []byte("BZhx"), ""},
+		{"short", []byte{0x28, 0xB5}, ""},
+		{"empty", nil, ""},
+	}
+	for _, compression := range fixtureCompressions {
+		data, err := os.ReadFile("testdata/image.img." + compression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests = append(tests, struct {
+			name string
+			head []byte
+			want string
+		}{compression + " fixture", data, compression})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			head := tt.head
+			if len(head) > sniffLen {
+				head = head[:sniffLen]
+			}
+			if got := sniffCompression(head); got != tt.want {
+				t.Errorf("sniffCompression(% x) = %q, want %q", head, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadImageSniffsCompression(t *testing.T) {
+	gz := compressTestImage(t, "gz")
+	xzData := compressTestImage(t, "xz")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/image.img.xz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(xzData)
+	})
+	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(gz)
+	})
+	mux.Handle("/latest", http.RedirectHandler("/images/v2.img", http.StatusFound))
+	mux.HandleFunc("/images/v2.img", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(gz)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		compression string
+		wantFile    string
+		wantSHA256  string
+	}{
+		{"presigned", "/image.img.xz?X-Amz-Signature=abc&X-Amz-Expires=60", "", "image.img", sha256Hex(testImage)},
+		{"no extension", "/download", "", "download.decompressed", sha256Hex(testImage)},
+		{"redirect", "/latest", "", "v2.img.decompressed", sha256Hex(testImage)},
+		{"explicit none", "/download", CompressionNone, "download", sha256Hex(gz)},
+		{"explicit gz", "/download", "gz", "download.decompressed", sha256Hex(testImage)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := DownloadImage(context.Background(), server.URL+tt.path, &DownloadOptions{
+				DestDir:     t.TempDir(),
+				Compression: tt.compression,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.SHA256 != tt.wantSHA256 {
+				t.Errorf("SHA256 = %s, want %s", result.SHA256, tt.wantSHA256)
+			}
+			if filepath.Base(result.Path) != tt.wantFile {
+				t.Errorf("image saved as %s, want %s", filepath.Base(result.Path), tt.wantFile)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"strings"
+	"syscall"
+)
+
+// Errors returned by Client operations, for use with errors.Is. The
+// returned error keeps the message and chain of the underlying failure.
+var (
+	// ErrAuthentication is returned when the BMC rejects the configured credentials.
+	ErrAuthentication = errors.New("authentication failed")
+	// ErrReadOnly is returned by mutating operations on a read-only client.
+	ErrReadOnly = errors.New("provider is in read-only mode")
+	// ErrUnreachable is returned when the BMC API or SSH port cannot be reached.
+	ErrUnreachable = errors.New("BMC unreachable")
+	// ErrNodeBusy is returned when another operation holds the node.
+	ErrNodeBusy = errors.New("node busy")
+	// ErrFlashInProgress is returned when the BMC is already flashing a node.
+	ErrFlashInProgress = errors.New("flash in progress")
+	// ErrChecksumMismatch is returned when an image does not match its checksum.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrSignatureInvalid is returned when a checksum file is not signed by a trusted key.
+	ErrSignatureInvalid = errors.New("invalid signature")
+	// ErrInsufficientSpace is returned when a disk on either side is full.
+	ErrInsufficientSpace = errors.New("insufficient space")
+	// ErrSSHAuthentication is returned when the BMC rejects the SSH credentials.
+	ErrSSHAuthentication = errors.New("SSH authentication failed")
+)
+
+// kindError tags err with one of the sentinel errors above without changing
+// its message.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// tagError marks err as being of kind. A nil err or kind returns err unchanged.
+func tagError(kind, err error) error {
+	if kind == nil || err == nil {
+		return err
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+// classifyBMCMessage returns the sentinel matching an error reported by
+// bmcd, or nil. bmcd only reports these conditions as free text.
+func classifyBMCMessage(msg string) error {
+	msg = strings.ToLower(msg)
+	switch {
+	case strings.Contains(msg, "in progress") || strings.Contains(msg, "already flashing"):
+		return ErrFlashInProgress
+	case strings.Contains(msg, "busy"):
+		return ErrNodeBusy
+	case strings.Contains(msg, "no space") || strings.Contains(msg, "not enough space") ||
+		strings.Contains(msg, "disk full"):
+		return ErrInsufficientSpace
+	case strings.Contains(msg, "checksum") || strings.Contains(msg, "crc") ||
+		strings.Contains(msg, "sha256 mismatch"):
+		return ErrChecksumMismatch
+	default:
+		return nil
+	}
+}
+
+// spaceError tags err with ErrInsufficientSpace when a write failed because
+// a disk is full, locally or on the BMC through SFTP.
+func spaceError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, syscall.ENOSPC) || strings.Contains(strings.ToLower(err.Error()), "no space left") {
+		return tagError(ErrInsufficientSpace, err)
+	}
+	return err
+}
+
+// isTLSError reports whether err is a certificate verification failure.
+func isTLSError(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	return errors.As(err, &verifyErr) || errors.As(err, &unknownAuthorityErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
@@ -0,0 +1,399 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+// Package fake provides an in-memory Turing Pi 2 BMC for unit tests.
+package fake
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/client"
+	tpi "github.com/davidroman0O/tpi/client"
+)
+
+// Ensure BMC satisfies client.BMC.
+var _ client.BMC = &BMC{}
+
+// FlashRecord describes a completed flash.
+type FlashRecord struct {
+	ImagePath string // Local path for FlashNode, BMC path for FlashNodeLocal
+	SHA256    string // Empty for FlashNodeLocal
+	SkipCRC   bool
+	OnBMC     bool // Flashed from the BMC filesystem
+}
+
+// BMC is an in-memory board. The zero value is not usable; create one with New.
+// All methods are safe for concurrent use.
+type BMC struct {
+	mu sync.Mutex
+
+	host         string
+	readOnly     bool
+	defaultCache string
+
+	power    map[int]bool
+	usb      tpi.UsbStatusInfo
+	info     map[string]string
+	about    map[string]string
+	flashed  map[int]FlashRecord
+	files    map[string][]byte
+	commands []string
+
+	calls    map[string]int
+	failures map[string][]error
+}
+
+// Option configures a fake BMC.
+type Option func(*BMC)
+
+// WithReadOnly makes the fake report read-only mode and refuse mutations
+// with client.ErrReadOnly, like a read-only client.
+func WithReadOnly() Option {
+	return func(b *BMC) { b.readOnly = true }
+}
+
+// WithDefaultCache sets the cache location reported for flashes that do not
+// choose one.
+func WithDefaultCache(location string) Option {
+	return func(b *BMC) { b.defaultCache = location }
+}
+
+// New returns a powered-off board with node 1 in USB device mode.
+func New(opts ...Option) *BMC {
+	b := &BMC{
+		host:         "fake-bmc",
+		defaultCache: client.CacheLocationNone,
+		power:        map[int]bool{1: false, 2: false, 3: false, 4: false},
+		usb:          tpi.UsbStatusInfo{Node: "Node 1", Mode: "Device", Route: "USB-A"},
+		info: map[string]string{
+			"api":       "1.1",
+			"buildtime": "2024-01-01 00:00:00",
+			"ip":        "192.0.2.10",
+			"mac":       "02:00:00:00:00:01",
+		},
+		about: map[string]string{
+			"api":           "1.1",
+			"version":       "2.0.5",
+			"build_version": "2024.01",
+			"buildroot":     "Buildroot 2024.01",
+		},
+		flashed:  map[int]FlashRecord{},
+		files:    map[string][]byte{},
+		calls:    map[string]int{},
+		failures: map[string][]error{},
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// FailNext makes the next len(errs) calls to method return errs in order.
+// method is the name of a client.BMC method without its Context suffix,
+// e.g. "PowerOn".
+func (b *BMC) FailNext(method string, errs ...error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures[method] = append(b.failures[method], errs...)
+}
+
+// Calls returns how many times method has been called, including failed
+// calls. method is named as for FailNext.
+func (b *BMC) Calls(method string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.calls[method]
+}
+
+// SetPower sets the power state of a node without going through PowerOn/PowerOff.
+func (b *BMC) SetPower(node int, on bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.power[node] = on
+}
+
+// Power returns the power state of a node.
+func (b *BMC) Power(node int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.power[node]
+}
+
+// USB returns the current USB configuration.
+func (b *BMC) USB() tpi.UsbStatusInfo {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.usb
+}
+
+// Flashed returns the last flash of a node, if any.
+func (b *BMC) Flashed(node int) (FlashRecord, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	record, ok := b.flashed[node]
+	return record, ok
+}
+
+// File returns the contents of a file on the BMC filesystem.
+func (b *BMC) File(remotePath string) ([]byte, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, ok := b.files[remotePath]
+	return data, ok
+}
+
+// Commands returns the command lines run through Run, in order.
+func (b *BMC) Commands() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.commands...)
+}
+
+// begin records a call and returns its programmed failure, if any, or the
+// error of a done ctx. The caller must hold b.mu.
+func (b *BMC) begin(ctx context.Context, method string, mutating bool) error {
+	b.calls[method]++
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if queued := b.failures[method]; len(queued) > 0 {
+		b.failures[method] = queued[1:]
+		return queued[0]
+	}
+	if mutating && b.readOnly {
+		return fmt.Errorf("%w: %s is not allowed", client.ErrReadOnly, method)
+	}
+	return nil
+}
+
+func validateNode(node int) error {
+	if node < 1 || node > 4 {
+		return fmt.Errorf("invalid node number: %d (must be 1-4)", node)
+	}
+	return nil
+}
+
+func (b *BMC) Address() string {
+	return b.host
+}
+
+func (b *BMC) ReadOnly() bool {
+	return b.readOnly
+}
+
+func (b *BMC) DefaultCacheLocation() string {
+	return b.defaultCache
+}
+
+func (b *BMC) NewSessionContext(ctx context.Context) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.begin(ctx, "NewSession", false); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("fake-session-%d", b.calls["NewSession"]), nil
+}
+
+func (b *BMC) PowerStatusContext(ctx context.Context) (map[int]bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.begin(ctx, "PowerStatus", false); err != nil {
+		return nil, err
+	}
+	status := make(map[int]bool, len(b.power))
+	for node, on := range b.power {
+		status[node] = on
+	}
+	return status, nil
+}
+
+func (b *BMC) PowerOnContext(ctx context.Context, node int) error {
+	return b.setPower(ctx, "PowerOn", node, true)
+}
+
+func (b *BMC) PowerOffContext(ctx context.Context, node int) error {
+	return b.setPower(ctx, "PowerOff", node, false)
+}
+
+func (b *BMC) setPower(ctx context.Context, method string, node int, on bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.begin(ctx, method, true); err != nil {
+		return err
+	}
+	if err := validateNode(node); err != nil {
+		return err
+	}
+	b.power[node] = on
+	return nil
+}
+
+func (b *BMC) UsbGetStatusContext(ctx context.Context) (*tpi.UsbStatusInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.begin(ctx, "UsbGetStatus", false); err != nil {
+		return nil, err
+	}
+	status := b.usb
+	return &status, nil
+}
+
+func (b *BMC) UsbSetHostContext(ctx context.Context, node int, bmc bool) error {
+	return b.setUsb(ctx, "UsbSetHost", node, "Host", bmc)
+}
+
+func (b *BMC) UsbSetDeviceContext(ctx context.Context, node int, bmc bool) error {
+	return b.setUsb(ctx, "UsbSetDevice", node, "Device", bmc)
+}
+
+func (b *BMC) UsbSetFlashContext(ctx context.Context, node int, bmc bool) error {
+	return b.setUsb(ctx, "UsbSetFlash", node, "Flash", bmc)
+}
+
+func (b *BMC) setUsb(ctx context.Context, method string, node int, mode string, bmc bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.begin(ctx, method, true); err != nil {
+		return err
+	}
+	if err := validateNode(node); err != nil {
+		return err
+	}
+	route := "USB-A"
+	if bmc {
+		route = "BMC"
+	}
+	b.usb = tpi.UsbStatusInfo{Node: fmt.Sprintf("Node %d", node), Mode: mode, Route: route}
+	return nil
+}
+
+func (b *BMC) InfoContext(ctx context.Context) (map[string]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.begin(ctx, "Info", false); err != nil {
+		return nil, err
+	}
+	return copyMap(b.info), nil
+}
+
+func (b *BMC) AboutContext(ctx context.Context) (map[string]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.begin(ctx, "About", false); err != nil {
+		return nil, err
+	}
+	return copyMap(b.about), nil
+}
+
+func (b *BMC) FlashNodeContext(ctx context.Context, node int, options *tpi.FlashOptions) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.begin(ctx, "FlashNode", true); err != nil {
+		return err
+	}
+	if err := validateNode(node); err != nil {
+		return err
+	}
+	if options == nil || options.ImagePath == "" {
+		return fmt.Errorf("image path is required")
+	}
+	if _, err := os.Stat(options.ImagePath); err != nil {
+		return fmt.Errorf("failed to open image: %w", err)
+	}
+	b.flashed[node] = FlashRecord{ImagePath: options.ImagePath, SHA256: options.SHA256, SkipCRC: options.SkipCRC}
+	return nil
+}
+
+func (b *BMC) FlashNodeLocalContext(ctx context.Context, node int, imagePath string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.begin(ctx, "FlashNodeLocal", true); err != nil {
+		return err
+	}
+	if err := validateNode(node); err != nil {
+		return err
+	}
+	if _, ok := b.files[imagePath]; !ok {
+		return fmt.Errorf("image %s not found on BMC", imagePath)
+	}
+	b.flashed[node] = FlashRecord{ImagePath: imagePath, OnBMC: true}
+	return nil
+}
+
+func (b *BMC) UploadFileContext(ctx context.Context, localPath, remotePath string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.begin(ctx, "UploadFile", true); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open local file: %w", err)
+	}
+	b.files[remotePath] = data
+	return nil
+}
+
+func (b *BMC) ListDirectoryContext(ctx context.Context, remotePath string) ([]tpi.FileInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.begin(ctx, "ListDirectory", false); err != nil {
+		return nil, err
+	}
+
+	var files []tpi.FileInfo
+	for p, data := range b.files {
+		if path.Dir(p) != path.Clean(remotePath) {
+			continue
+		}
+		files = append(files, tpi.FileInfo{
+			Name:    path.Base(p),
+			Size:    int64(len(data)),
+			Mode:    0644,
+			ModTime: time.Unix(0, 0).UTC(),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+// RunContext records the command line of cmd. "rm -rf" removes the files
+// under its operands; every other command succeeds without output.
+func (b *BMC) RunContext(ctx context.Context, cmd client.Command) (*client.CommandResult, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.begin(ctx, "Run", true); err != nil {
+		return nil, err
+	}
+	b.commands = append(b.commands, cmd.String())
+
+	if cmd.Name == "rm" {
+		for _, arg := range cmd.Args {
+			if strings.HasPrefix(arg, "-") {
+				continue
+			}
+			dir := path.Clean(arg)
+			for p := range b.files {
+				if p == dir || strings.HasPrefix(p, dir+"/") {
+					delete(b.files, p)
+				}
+			}
+		}
+	}
+	return &client.CommandResult{}, nil
+}
+
+func copyMap(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,266 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tpi "github.com/davidroman0O/tpi/client"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// flashPollInterval is the delay between flash progress requests, a
+// variable so tests can poll faster.
+var flashPollInterval = time.Second
+
+const (
+	// flashPollTimeout bounds a single progress request; the BMC answers
+	// slowly while it is writing to the node.
+	flashPollTimeout = 45 * time.Second
+	// flashMaxPollErrors is how many consecutive progress errors are tolerated.
+	flashMaxPollErrors = 20
+)
+
+// flashTransfer is an image about to be streamed to a node.
+type flashTransfer struct {
+	node     int
+	options  *tpi.FlashOptions
+	fileName string
+	fileSize int64
+}
+
+// prepareFlash validates a flash and checks the image against
+// options.SHA256 before anything is sent to the BMC.
+func (a *apiClient) prepareFlash(ctx context.Context, node int, options *tpi.FlashOptions) (*flashTransfer, error) {
+	if err := validateNode(node); err != nil {
+		return nil, err
+	}
+	if options == nil || options.ImagePath == "" {
+		return nil, fmt.Errorf("image path is required")
+	}
+
+	stat, err := os.Stat(options.ImagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image file info: %w", err)
+	}
+	setSpanAttributes(ctx, attrBytes.Int64(stat.Size()))
+
+	if options.SHA256 != "" {
+		calculated, err := calculateSHA256(options.ImagePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate SHA256: %w", err)
+		}
+		if calculated != options.SHA256 {
+			return nil, fmt.Errorf("%w: provided SHA256 %s, calculated %s", ErrChecksumMismatch, options.SHA256, calculated)
+		}
+	}
+
+	return &flashTransfer{
+		node:     node,
+		options:  options,
+		fileName: filepath.Base(options.ImagePath),
+		fileSize: stat.Size(),
+	}, nil
+}
+
+// startFlash announces the transfer and returns its handle. Nothing is
+// written to the node yet, so this is the only step that may be retried.
+func (a *apiClient) startFlash(ctx context.Context, t *flashTransfer) (int, error) {
+	params := url.Values{}
+	params.Set("file", t.fileName)
+	params.Set("length", strconv.FormatInt(t.fileSize, 10))
+	params.Set("node", strconv.Itoa(t.node-1)) // BMC uses 0-based indexing
+	if t.options.SHA256 != "" {
+		params.Set("sha256", t.options.SHA256)
+	}
+	if t.options.SkipCRC {
+		params.Set("skip_crc", "1")
+	}
+
+	body, err := a.call(ctx, "set", "flash", params)
+	if err != nil {
+		return 0, fmt.Errorf("failed to initiate flash operation: %w", err)
+	}
+
+	var handleResp struct {
+		Handle *int `json:"handle"`
+	}
+	if err := json.Unmarshal(body, &handleResp); err != nil || handleResp.Handle == nil {
+		return 0, fmt.Errorf("invalid response: missing handle")
+	}
+	return *handleResp.Handle, nil
+}
+
+// runFlash uploads the image for handle and waits until the BMC has written
+// it to the node. Failures are final: the BMC may already be writing the
+// node, and starting over would upload the image again.
+func (a *apiClient) runFlash(ctx context.Context, handle int, t *flashTransfer) error {
+	if err := a.uploadImage(ctx, handle, t.options.ImagePath, t.fileName, t.fileSize); err != nil {
+		return a.abortIfCancelled(ctx, err)
+	}
+	return a.abortIfCancelled(ctx, a.watchFlash(ctx, handle))
+}
+
+// uploadImage streams the image as a multipart form without buffering it in
+// memory. The multipart framing is precomputed so the request carries an
+// exact Content-Length and can be replayed after re-authentication.
+func (a *apiClient) uploadImage(ctx context.Context, handle int, imagePath, fileName string, fileSize int64) error {
+	var head bytes.Buffer
+	writer := multipart.NewWriter(&head)
+	if _, err := writer.CreateFormFile("file", fileName); err != nil {
+		return fmt.Errorf("failed to create form file: %w", err)
+	}
+	headLen := head.Len()
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to close multipart writer: %w", err)
+	}
+	framing := head.Bytes()
+	prefix, suffix := framing[:headLen], framing[headLen:]
+
+	newBody := func() (io.ReadCloser, error) {
+		file, err := os.Open(imagePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open image file: %w", err)
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(prefix), file, bytes.NewReader(suffix)), file}, nil
+	}
+
+	body, err := newBody()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint(fmt.Sprintf("/api/bmc/upload/%d", handle), nil), body)
+	if err != nil {
+		body.Close()
+		return fmt.Errorf("failed to create upload request: %w", err)
+	}
+	req.ContentLength = int64(len(prefix)) + fileSize + int64(len(suffix))
+	req.GetBody = newBody
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := a.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		statusErr := &StatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(respBody))}
+		return fmt.Errorf("failed to upload image: %w", tagError(classifyBMCMessage(statusErr.Body), statusErr))
+	}
+
+	return nil
+}
+
+// watchFlash polls the flash status until the transfer identified by handle
+// is done or fails.
+func (a *apiClient) watchFlash(ctx context.Context, handle int) error {
+	ticker := time.NewTicker(flashPollInterval)
+	defer ticker.Stop()
+
+	consecutiveErr := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		pollCtx, cancel := context.WithTimeout(ctx, flashPollTimeout)
+		body, err := a.get(pollCtx, "flash", nil)
+		cancel()
+		if err != nil {
+			consecutiveErr++
+			if consecutiveErr >= flashMaxPollErrors {
+				return fmt.Errorf("too many consecutive errors while checking flash progress (%d): %w", consecutiveErr, err)
+			}
+			continue
+		}
+		consecutiveErr = 0
+
+		// Anything other than Done or Error (Transferring, or an unknown
+		// status while the BMC verifies the image) means keep waiting
+		var status struct {
+			Done  json.RawMessage `json:"Done"`
+			Error json.RawMessage `json:"Error"`
+		}
+		if err := json.Unmarshal(body, &status); err != nil {
+			continue
+		}
+
+		if status.Error != nil {
+			err := fmt.Errorf("error occurred during flashing (handle %d): %s", handle, string(status.Error))
+			return tagError(classifyBMCMessage(string(status.Error)), err)
+		}
+		if status.Done != nil {
+			return nil
+		}
+	}
+}
+
+// flashNodeLocal flashes an image that already resides on the BMC filesystem.
+func (a *apiClient) flashNodeLocal(ctx context.Context, node int, imagePath string) error {
+	if err := validateNode(node); err != nil {
+		return err
+	}
+	if imagePath == "" {
+		return fmt.Errorf("image path is required")
+	}
+
+	params := url.Values{}
+	params.Set("node", strconv.Itoa(node-1)) // BMC uses 0-based indexing
+	params.Set("path", imagePath)
+
+	if err := a.set(ctx, "update", params); err != nil {
+		return fmt.Errorf("flash operation failed: %w", a.abortIfCancelled(ctx, err))
+	}
+
+	return nil
+}
+
+// abortIfCancelled cancels the flash running on the BMC when err was caused
+// by ctx ending, so the node is not left being written after the provider
+// gave up. Other errors are returned unchanged.
+func (a *apiClient) abortIfCancelled(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+
+	// ctx is already done; the abort request gets its own deadline
+	abortCtx := context.WithoutCancel(ctx)
+	if abortErr := a.set(abortCtx, "cancel", nil); abortErr != nil {
+		tflog.Warn(ctx, "Failed to cancel flash on the BMC", map[string]interface{}{
+			"error": abortErr.Error(),
+		})
+		return fmt.Errorf("flash cancelled (%w), but the BMC did not confirm aborting it: %v", context.Cause(ctx), abortErr)
+	}
+
+	tflog.Info(ctx, "Cancelled flash on the BMC")
+	return fmt.Errorf("flash cancelled: %w", context.Cause(ctx))
+}
+
+// validateNode checks that node is a valid Turing Pi 2 slot.
+func validateNode(node int) error {
+	if node < 1 || node > 4 {
+		return fmt.Errorf("invalid node number: %d (must be between 1 and 4)", node)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// HostKeyError is returned when the BMC presents an SSH host key that does not
+// match the configured known_hosts file or pinned fingerprint. The connection
+// is aborted before any credentials are sent.
+type HostKeyError struct {
+	Host        string // host:port that was dialed
+	Fingerprint string // SHA256 fingerprint of the key the server presented
+	Reason      string
+}
+
+func (e *HostKeyError) Error() string {
+	return fmt.Sprintf("SSH host key verification failed for %s (presented key %s): %s", e.Host, e.Fingerprint, e.Reason)
+}
+
+// IsHostKeyError reports whether err is (or wraps) a HostKeyError.
+func IsHostKeyError(err error) bool {
+	var hostKeyErr *HostKeyError
+	return errors.As(err, &hostKeyErr)
+}
+
+// sshHostKeyCallback returns the host key verification policy for BMC
+// connections. When both a known_hosts file and a fingerprint are configured,
+// the key must satisfy both. When neither is configured any key is accepted,
+// which preserves the behavior of earlier provider versions.
+func (c *Client) sshHostKeyCallback() (ssh.HostKeyCallback, error) {
+	var callbacks []ssh.HostKeyCallback
+
+	if c.SSHKnownHostsFile != "" {
+		knownHostsPath, err := expandHome(c.SSHKnownHostsFile)
+		if err != nil {
+			return nil, err
+		}
+		callback, err := knownhosts.New(knownHostsPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load SSH known_hosts file: %w", err)
+		}
+		callbacks = append(callbacks, knownHostsCallback(callback))
+	}
+
+	if c.SSHHostKeyFingerprint != "" {
+		callbacks = append(callbacks, fingerprintCallback(c.SSHHostKeyFingerprint))
+	}
+
+	if len(callbacks) == 0 {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		for _, callback := range callbacks {
+			if err := callback(hostname, remote, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// knownHostsCallback converts knownhosts errors into a HostKeyError.
+func knownHostsCallback(callback ssh.HostKeyCallback) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := callback(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+
+		reason := "host is not present in the known_hosts file"
+		if len(keyErr.Want) > 0 {
+			reason = fmt.Sprintf("key does not match the entry at %s:%d; the BMC may have been replaced or its address spoofed",
+				keyErr.Want[0].Filename, keyErr.Want[0].Line)
+		}
+
+		return &HostKeyError{
+			Host:        hostname,
+			Fingerprint: ssh.FingerprintSHA256(key),
+			Reason:      reason,
+		}
+	}
+}
+
+// fingerprintCallback accepts only the key whose SHA256 fingerprint matches.
+// The "SHA256:" prefix is optional so values can be pasted from either
+// ssh-keygen -lf or ssh-keyscan output.
+func fingerprintCallback(fingerprint string) ssh.HostKeyCallback {
+	want := NormalizeHostKeyFingerprint(fingerprint)
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		got := ssh.FingerprintSHA256(key)
+		if got == want {
+			return nil
+		}
+		return &HostKeyError{
+			Host:        hostname,
+			Fingerprint: got,
+			Reason:      fmt.Sprintf("key does not match the pinned fingerprint %s", want),
+		}
+	}
+}
+
+// NormalizeHostKeyFingerprint returns fingerprint in the "SHA256:<base64>"
+// form produced by ssh-keygen, without base64 padding.
+func NormalizeHostKeyFingerprint(fingerprint string) string {
+	fingerprint = strings.TrimSpace(fingerprint)
+	fingerprint = strings.TrimPrefix(fingerprint, "SHA256:")
+	return "SHA256:" + strings.TrimRight(fingerprint, "=")
+}
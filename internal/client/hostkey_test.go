@@ -0,0 +1,90 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func newTestHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestHostKeyCallbackFingerprint(t *testing.T) {
+	trusted := newTestHostKey(t)
+	spoofed := newTestHostKey(t)
+	remote := &net.TCPAddr{IP: net.ParseIP("192.168.1.90"), Port: 22}
+
+	c := &Client{SSHHostKeyFingerprint: ssh.FingerprintSHA256(trusted)}
+	callback, err := c.sshHostKeyCallback()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := callback("192.168.1.90:22", remote, trusted); err != nil {
+		t.Errorf("trusted key rejected: %v", err)
+	}
+	if err := callback("192.168.1.90:22", remote, spoofed); !IsHostKeyError(err) {
+		t.Errorf("spoofed key: expected HostKeyError, got %v", err)
+	}
+}
+
+func TestHostKeyCallbackKnownHosts(t *testing.T) {
+	trusted := newTestHostKey(t)
+	spoofed := newTestHostKey(t)
+	remote := &net.TCPAddr{IP: net.ParseIP("192.168.1.90"), Port: 22}
+
+	knownHostsFile := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{"192.168.1.90"}, trusted) + "\n"
+	if err := os.WriteFile(knownHostsFile, []byte(line), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{SSHKnownHostsFile: knownHostsFile}
+	callback, err := c.sshHostKeyCallback()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := callback("192.168.1.90:22", remote, trusted); err != nil {
+		t.Errorf("trusted key rejected: %v", err)
+	}
+	if err := callback("192.168.1.90:22", remote, spoofed); !IsHostKeyError(err) {
+		t.Errorf("spoofed key: expected HostKeyError, got %v", err)
+	}
+	if err := callback("192.168.1.91:22", remote, trusted); !IsHostKeyError(err) {
+		t.Errorf("unknown host: expected HostKeyError, got %v", err)
+	}
+}
+
+func TestNormalizeHostKeyFingerprint(t *testing.T) {
+	const want = "SHA256:uNiVztksCsDhcc0u9e8BujQXVUpKZIDTMczCvj3tD2s"
+	for _, in := range []string{
+		want,
+		"uNiVztksCsDhcc0u9e8BujQXVUpKZIDTMczCvj3tD2s",
+		"SHA256:uNiVztksCsDhcc0u9e8BujQXVUpKZIDTMczCvj3tD2s=",
+		" " + want + "\n",
+	} {
+		if got := NormalizeHostKeyFingerprint(in); got != want {
+			t.Errorf("NormalizeHostKeyFingerprint(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
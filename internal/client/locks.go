@@ -0,0 +1,169 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// DefaultLockTimeout is how long an operation waits for a busy board or node
+// before giving up. It matches the default node_flash create timeout so a
+// queued flash can outlast the one ahead of it.
+const DefaultLockTimeout = 3 * time.Hour
+
+// LockTimeoutError is returned when a lock could not be acquired in time.
+// It matches ErrFlashInProgress when a flash holds the lock and ErrNodeBusy
+// otherwise.
+type LockTimeoutError struct {
+	Resource  string // "board" or "node N"
+	Operation string
+	Holder    string // Operation holding the lock when the wait gave up
+	Waited    time.Duration
+}
+
+func (e *LockTimeoutError) Error() string {
+	holder := "another operation on the same BMC"
+	if e.Holder != "" {
+		holder = e.Holder
+	}
+	return fmt.Sprintf("timed out after %s waiting for the %s lock to %s; %s is still running",
+		e.Waited, e.Resource, e.Operation, holder)
+}
+
+func (e *LockTimeoutError) Unwrap() error {
+	if e.Holder == "FlashNode" || e.Holder == "FlashNodeLocal" {
+		return ErrFlashInProgress
+	}
+	return ErrNodeBusy
+}
+
+// LockManager serializes mutating BMC operations issued from this provider
+// process. Terraform runs resources in parallel, but the BMC can only run one
+// flash at a time and USB routing is a single shared bus, so those take the
+// board lock; power changes only take the lock of the node they affect.
+// When both are needed the board lock is always acquired first.
+type LockManager struct {
+	board        chan struct{}
+	nodes        map[int]chan struct{}
+	boardTimeout time.Duration
+	nodeTimeout  time.Duration
+
+	mu      sync.Mutex
+	holders map[string]string // Lock name to the operation holding it
+}
+
+// NewLockManager creates a lock manager. Zero timeouts use DefaultLockTimeout.
+func NewLockManager(boardTimeout, nodeTimeout time.Duration) *LockManager {
+	if boardTimeout <= 0 {
+		boardTimeout = DefaultLockTimeout
+	}
+	if nodeTimeout <= 0 {
+		nodeTimeout = DefaultLockTimeout
+	}
+
+	nodes := make(map[int]chan struct{}, 4)
+	for node := 1; node <= 4; node++ {
+		nodes[node] = make(chan struct{}, 1)
+	}
+
+	return &LockManager{
+		board:        make(chan struct{}, 1),
+		nodes:        nodes,
+		boardTimeout: boardTimeout,
+		nodeTimeout:  nodeTimeout,
+		holders:      make(map[string]string),
+	}
+}
+
+// LockBoard acquires the board-wide lock. The returned function releases it.
+func (m *LockManager) LockBoard(ctx context.Context, operation string) (func(), error) {
+	return m.acquire(ctx, m.board, "board", operation, m.boardTimeout)
+}
+
+// LockNode acquires the lock for a single node. The returned function releases it.
+func (m *LockManager) LockNode(ctx context.Context, node int, operation string) (func(), error) {
+	lock, ok := m.nodes[node]
+	if !ok {
+		return nil, validateNode(node)
+	}
+	return m.acquire(ctx, lock, fmt.Sprintf("node %d", node), operation, m.nodeTimeout)
+}
+
+// LockBoardAndNode acquires the board lock, then the node lock.
+func (m *LockManager) LockBoardAndNode(ctx context.Context, node int, operation string) (func(), error) {
+	unlockBoard, err := m.LockBoard(ctx, operation)
+	if err != nil {
+		return nil, err
+	}
+	unlockNode, err := m.LockNode(ctx, node, operation)
+	if err != nil {
+		unlockBoard()
+		return nil, err
+	}
+	return func() {
+		unlockNode()
+		unlockBoard()
+	}, nil
+}
+
+// acquire takes the semaphore lock, waiting at most timeout.
+func (m *LockManager) acquire(ctx context.Context, lock chan struct{}, resource, operation string, timeout time.Duration) (func(), error) {
+	acquired := func() func() {
+		m.setHolder(resource, operation)
+		return func() {
+			m.setHolder(resource, "")
+			<-lock
+		}
+	}
+
+	select {
+	case lock <- struct{}{}:
+		return acquired(), nil
+	default:
+	}
+
+	tflog.Info(ctx, "Waiting for BMC lock", map[string]interface{}{
+		"lock":      resource,
+		"operation": operation,
+	})
+
+	start := time.Now()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case lock <- struct{}{}:
+		tflog.Debug(ctx, "Acquired BMC lock", map[string]interface{}{
+			"lock":      resource,
+			"operation": operation,
+			"waited":    time.Since(start).String(),
+		})
+		return acquired(), nil
+	case <-timer.C:
+		return nil, &LockTimeoutError{Resource: resource, Operation: operation, Holder: m.holder(resource), Waited: timeout}
+	case <-ctx.Done():
+		return nil, fmt.Errorf("cancelled while waiting for the %s lock: %w", resource, ctx.Err())
+	}
+}
+
+func (m *LockManager) setHolder(resource, operation string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if operation == "" {
+		delete(m.holders, resource)
+		return
+	}
+	m.holders[resource] = operation
+}
+
+func (m *LockManager) holder(resource string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.holders[resource]
+}
@@ -0,0 +1,94 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLockManagerTimeout(t *testing.T) {
+	m := NewLockManager(20*time.Millisecond, 20*time.Millisecond)
+	ctx := context.Background()
+
+	unlock, err := m.LockBoardAndNode(ctx, 1, "FlashNode")
+	if err != nil {
+		t.Fatalf("LockBoardAndNode: %v", err)
+	}
+
+	var timeoutErr *LockTimeoutError
+	if _, err := m.LockBoard(ctx, "UsbSetHost"); !errors.As(err, &timeoutErr) {
+		t.Errorf("LockBoard while flashing: got %v, want LockTimeoutError", err)
+	} else if timeoutErr.Holder != "FlashNode" || !errors.Is(err, ErrFlashInProgress) {
+		t.Errorf("LockBoard while flashing: holder %q, got %v, want ErrFlashInProgress", timeoutErr.Holder, err)
+	}
+	if _, err := m.LockNode(ctx, 1, "PowerOn"); !errors.As(err, &timeoutErr) {
+		t.Errorf("LockNode(1) while flashing node 1: got %v, want LockTimeoutError", err)
+	}
+
+	unlockOther, err := m.LockNode(ctx, 2, "PowerOn")
+	if err != nil {
+		t.Errorf("LockNode(2) while flashing node 1: %v", err)
+	} else {
+		if _, err := m.LockNode(ctx, 2, "PowerOff"); !errors.Is(err, ErrNodeBusy) {
+			t.Errorf("LockNode(2) while powering on node 2: got %v, want ErrNodeBusy", err)
+		}
+		unlockOther()
+	}
+
+	unlock()
+	unlockBoard, err := m.LockBoard(ctx, "UsbSetHost")
+	if err != nil {
+		t.Fatalf("LockBoard after release: %v", err)
+	}
+	unlockBoard()
+}
+
+func TestLockManagerQueues(t *testing.T) {
+	m := NewLockManager(time.Second, time.Second)
+	ctx := context.Background()
+
+	unlock, err := m.LockNode(ctx, 3, "PowerOff")
+	if err != nil {
+		t.Fatalf("LockNode: %v", err)
+	}
+
+	acquired := make(chan error, 1)
+	go func() {
+		unlock, err := m.LockNode(ctx, 3, "PowerOn")
+		if err == nil {
+			unlock()
+		}
+		acquired <- err
+	}()
+
+	select {
+	case err := <-acquired:
+		t.Fatalf("second lock acquired while held: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	unlock()
+	if err := <-acquired; err != nil {
+		t.Errorf("queued lock: %v", err)
+	}
+}
+
+func TestLockManagerCancel(t *testing.T) {
+	m := NewLockManager(time.Minute, time.Minute)
+
+	unlock, err := m.LockBoard(context.Background(), "FlashNode")
+	if err != nil {
+		t.Fatalf("LockBoard: %v", err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.LockBoard(ctx, "UsbSetFlash"); !errors.Is(err, context.Canceled) {
+		t.Errorf("LockBoard with cancelled context: got %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// SupportedAPIMajorVersion is the bmcd API major version this client speaks.
+// Any minor version of it is accepted.
+const SupportedAPIMajorVersion = 1
+
+// PreflightCheck names the part of the connection a preflight failure is about.
+type PreflightCheck string
+
+const (
+	PreflightConnect    PreflightCheck = "connect"     // BMC API unreachable or not answering
+	PreflightTLS        PreflightCheck = "tls"         // BMC certificate failed verification
+	PreflightAuth       PreflightCheck = "auth"        // BMC API rejected the credentials
+	PreflightAPIVersion PreflightCheck = "api_version" // Firmware API version outside the supported range
+	PreflightSSH        PreflightCheck = "ssh"         // SSH port unreachable
+	PreflightSSHHostKey PreflightCheck = "ssh_host_key"
+	PreflightSSHAuth    PreflightCheck = "ssh_auth"
+)
+
+// PreflightError describes one failed preflight check.
+type PreflightError struct {
+	Check PreflightCheck
+	Err   error
+}
+
+func (e *PreflightError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PreflightError) Unwrap() error {
+	return e.Err
+}
+
+// Preflight verifies that the BMC is reachable, accepts the credentials and
+// runs a supported firmware API version. When checkSSH is set it also opens
+// an SSH connection, which verifies the host key and SSH credentials.
+// API checks stop at the first failure; the SSH check runs regardless.
+func (c *Client) Preflight(checkSSH bool) []*PreflightError {
+	return c.PreflightContext(c.ctx, checkSSH)
+}
+
+// PreflightContext is Preflight with a context that aborts the checks, so an
+// unreachable BMC does not hold up Configure for the whole retry policy.
+func (c *Client) PreflightContext(ctx context.Context, checkSSH bool) []*PreflightError {
+	var failures []*PreflightError
+	if err := c.preflightAPI(ctx); err != nil {
+		failures = append(failures, err)
+	}
+	if checkSSH && ctx.Err() == nil {
+		if err := c.preflightSSH(ctx); err != nil {
+			failures = append(failures, err)
+		}
+	}
+	return failures
+}
+
+// preflightAPI authenticates and checks the firmware API version.
+func (c *Client) preflightAPI(ctx context.Context) *PreflightError {
+	// With only a token there is nothing to log in with; reading info below
+	// verifies the token instead
+	if c.api.hasCredentials() {
+		_, err := withRetry(ctx, c, "Authenticate", func(ctx context.Context) (string, error) {
+			return c.api.login(ctx, true)
+		})
+		if err != nil {
+			return &PreflightError{Check: classifyAPIError(err), Err: err}
+		}
+	}
+
+	info, err := c.InfoContext(ctx)
+	if err != nil {
+		return &PreflightError{Check: classifyAPIError(err), Err: fmt.Errorf("failed to read BMC info: %w", err)}
+	}
+	about, err := c.AboutContext(ctx)
+	if err != nil {
+		return &PreflightError{Check: PreflightConnect, Err: fmt.Errorf("failed to read BMC about info: %w", err)}
+	}
+
+	version := info["api"]
+	if version == "" {
+		version = about["api"]
+	}
+	if version == "" {
+		tflog.Warn(ctx, "BMC did not report an API version; skipping compatibility check", map[string]interface{}{
+			"firmware": about["version"],
+		})
+		return nil
+	}
+	if err := checkAPIVersion(version); err != nil {
+		return &PreflightError{Check: PreflightAPIVersion, Err: err}
+	}
+
+	tflog.Debug(ctx, "BMC API preflight passed", map[string]interface{}{
+		"api_version": version,
+		"firmware":    about["version"],
+	})
+	return nil
+}
+
+// preflightSSH opens the pooled SSH connection, which later operations reuse.
+func (c *Client) preflightSSH(ctx context.Context) *PreflightError {
+	if _, err := c.sshConn(ctx); err != nil {
+		return &PreflightError{Check: classifySSHError(err), Err: err}
+	}
+	return nil
+}
+
+// checkAPIVersion checks a "major.minor" bmcd API version against
+// SupportedAPIMajorVersion.
+func checkAPIVersion(version string) error {
+	majorStr, _, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(version), "v"), ".")
+	major, err := strconv.Atoi(majorStr)
+	if err != nil {
+		return fmt.Errorf("BMC reported an unrecognized API version %q", version)
+	}
+	if major != SupportedAPIMajorVersion {
+		return fmt.Errorf("BMC firmware API version %s is not supported; this provider supports API %d.x",
+			version, SupportedAPIMajorVersion)
+	}
+	return nil
+}
+
+// classifyAPIError tells authentication and certificate failures apart from
+// connectivity problems.
+func classifyAPIError(err error) PreflightCheck {
+	switch {
+	case errors.Is(err, ErrAuthentication):
+		return PreflightAuth
+	case isTLSError(err):
+		return PreflightTLS
+	default:
+		return PreflightConnect
+	}
+}
+
+// classifySSHError tells host key and authentication failures apart from
+// connectivity problems.
+func classifySSHError(err error) PreflightCheck {
+	switch {
+	case IsHostKeyError(err):
+		return PreflightSSHHostKey
+	case errors.Is(err, ErrSSHAuthentication):
+		return PreflightSSHAuth
+	default:
+		return PreflightSSH
+	}
+}
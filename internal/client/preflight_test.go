@@ -0,0 +1,132 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// preflightBMC serves the authenticate and info endpoints of a BMC that
+// accepts password "turing" and reports apiVersion.
+func preflightBMC(t *testing.T, apiVersion string) *Client {
+	t.Helper()
+
+	bmc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/bmc/authenticate":
+			body, _ := io.ReadAll(r.Body)
+			if !strings.Contains(string(body), `"password":"turing"`) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, `{"id":"token"}`)
+		case r.URL.Query().Get("type") == "other":
+			fmt.Fprintf(w, `{"response":[{"result":{"api":%q}}]}`, apiVersion)
+		case r.URL.Query().Get("type") == "about":
+			fmt.Fprint(w, `{"response":[{"result":{"version":"2.0.5"}}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(bmc.Close)
+
+	c, err := NewClient(context.Background(), Config{
+		Host:     strings.TrimPrefix(bmc.URL, "http://"),
+		Username: "root",
+		Password: "turing",
+		Scheme:   SchemeHTTP,
+		Retry:    RetryPolicy{MaxAttempts: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestPreflight(t *testing.T) {
+	if failures := preflightBMC(t, "1.1").Preflight(false); len(failures) != 0 {
+		t.Errorf("supported BMC: got failures %v", failures)
+	}
+
+	wrongPassword := preflightBMC(t, "1.1")
+	wrongPassword.api.password = "wrong"
+	failures := wrongPassword.Preflight(false)
+	if len(failures) != 1 || failures[0].Check != PreflightAuth {
+		t.Errorf("wrong password: got failures %v, want one %s failure", failures, PreflightAuth)
+	}
+
+	failures = preflightBMC(t, "2.0").Preflight(false)
+	if len(failures) != 1 || failures[0].Check != PreflightAPIVersion {
+		t.Errorf("API 2.0: got failures %v, want one %s failure", failures, PreflightAPIVersion)
+	}
+
+	unreachable, err := NewClient(context.Background(), Config{
+		Host:   "127.0.0.1:1",
+		Scheme: SchemeHTTP,
+		Retry:  RetryPolicy{MaxAttempts: 1, InitialBackoff: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failures = unreachable.Preflight(false)
+	if len(failures) != 1 || failures[0].Check != PreflightConnect {
+		t.Errorf("unreachable BMC: got failures %v, want one %s failure", failures, PreflightConnect)
+	}
+}
+
+func TestPreflightContextCancelled(t *testing.T) {
+	var requests int
+	bmc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer bmc.Close()
+
+	// Without the deadline, the retry policy alone would wait for an hour
+	c, err := NewClient(context.Background(), Config{
+		Host:   strings.TrimPrefix(bmc.URL, "http://"),
+		Token:  "token",
+		Scheme: SchemeHTTP,
+		Retry:  RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	failures := c.PreflightContext(ctx, true)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("PreflightContext took %s after its context ended", elapsed)
+	}
+	if len(failures) != 1 || failures[0].Check != PreflightConnect {
+		t.Errorf("got failures %v, want one %s failure and no SSH check", failures, PreflightConnect)
+	}
+	if requests != 1 {
+		t.Errorf("BMC received %d requests, want 1", requests)
+	}
+}
+
+func TestCheckAPIVersion(t *testing.T) {
+	for version, ok := range map[string]bool{
+		"1.0": true,
+		"1.1": true,
+		"1":   true,
+		"2.0": false,
+		"0.9": false,
+		"abc": false,
+	} {
+		if err := checkAPIVersion(version); (err == nil) != ok {
+			t.Errorf("checkAPIVersion(%q) = %v, want ok=%v", version, err, ok)
+		}
+	}
+}
@@ -0,0 +1,423 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// A partial download is kept in the resume directory as <key>.part, the bytes
+// received so far, next to <key>.json, the partialState they belong to. The
+// key is derived from the URL and the content's validator, so different builds
+// served from one URL, such as nightlies, each keep their own partial download.
+// The most recent partial download of a URL is offered to the server with
+// If-Range, and the server's validators make sure a resumed range comes from
+// the same content before it is appended. Once a download of a URL completes,
+// the partial downloads of its other content are removed.
+
+// partialState describes the content a partial download belongs to.
+type partialState struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Size         int64  `json:"size"` // Total size, or -1 when unknown
+}
+
+// validator returns the If-Range value identifying the content: a strong ETag
+// or, failing that, the Last-Modified date.
+func (s partialState) validator() string {
+	if s.ETag != "" && !strings.HasPrefix(s.ETag, "W/") {
+		return s.ETag
+	}
+	return s.LastModified
+}
+
+// partialDownload is a download whose received bytes are appended to a part
+// file, so it can be resumed if it is interrupted.
+type partialDownload struct {
+	dir    string
+	key    string
+	state  partialState
+	offset int64 // Bytes on disk before this request
+	file   *os.File
+
+	bodyErr error  // Read error from the server; the part file is kept when set
+	keepAs  string // Where to move the part file once complete, if anywhere
+}
+
+// activePartials holds the keys of partial downloads in progress, so parallel
+// downloads of the same content do not write to the same part file.
+var activePartials = struct {
+	sync.Mutex
+	keys map[string]bool
+}{keys: make(map[string]bool)}
+
+func claimPartial(key string) bool {
+	activePartials.Lock()
+	defer activePartials.Unlock()
+	if activePartials.keys[key] {
+		return false
+	}
+	activePartials.keys[key] = true
+	return true
+}
+
+func releasePartial(key string) {
+	activePartials.Lock()
+	defer activePartials.Unlock()
+	delete(activePartials.keys, key)
+}
+
+// partialKey names the partial download of the content of url identified by
+// validator.
+func partialKey(url, validator string) string {
+	sum := sha256.Sum256([]byte(url + "\n" + validator))
+	return hex.EncodeToString(sum[:16])
+}
+
+// partialsOf returns the keys of the partial downloads of url recorded in dir,
+// most recently written first.
+func partialsOf(dir, url string) []string {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	var keys []string
+	modified := make(map[string]time.Time)
+	for _, path := range paths {
+		var state partialState
+		data, err := os.ReadFile(path)
+		if err != nil || json.Unmarshal(data, &state) != nil || state.URL != url {
+			continue
+		}
+		stat, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		key := strings.TrimSuffix(filepath.Base(path), ".json")
+		keys = append(keys, key)
+		modified[key] = stat.ModTime()
+	}
+	sort.Slice(keys, func(i, j int) bool { return modified[keys[i]].After(modified[keys[j]]) })
+	return keys
+}
+
+func (p *partialDownload) path(ext string) string {
+	return filepath.Join(p.dir, p.key+ext)
+}
+
+// openDownload requests url. With a resumeDir, it continues the partial
+// download recorded there for the content the server has, and otherwise
+// starts a new one if the response can be resumed later. The returned partial
+// download, if any, must be finished by the caller.
+func openDownload(ctx context.Context, url, resumeDir string) (*http.Response, *partialDownload, error) {
+	var part *partialDownload
+	var keys []string
+	if resumeDir != "" {
+		keys = partialsOf(resumeDir, url)
+	}
+	if len(keys) > 0 {
+		part = claimRecorded(resumeDir, keys[0], url)
+	}
+
+	resp, part, err := requestResumed(ctx, url, part)
+	if err == nil && part == nil && len(keys) > 1 && resp.StatusCode == http.StatusOK {
+		// The server has other content than the latest partial download;
+		// resume the one recorded for that content, if any
+		if validator := contentValidator(resp); validator != "" {
+			if other := claimRecorded(resumeDir, partialKey(url, validator), url); other != nil {
+				resp.Body.Close()
+				resp, part, err = requestResumed(ctx, url, other)
+			}
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if part != nil {
+		tflog.Info(ctx, "Resuming partial download", map[string]interface{}{
+			"url":    redactURL(url),
+			"offset": part.offset,
+		})
+		err = part.open(0)
+	} else if resumeDir != "" && resumable(resp) {
+		key := partialKey(url, contentValidator(resp))
+		if !claimPartial(key) {
+			return resp, nil, nil
+		}
+		part = &partialDownload{dir: resumeDir, key: key}
+		part.state = partialState{
+			URL:          url,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			Size:         resp.ContentLength,
+		}
+		err = part.open(os.O_TRUNC)
+	} else {
+		return resp, nil, nil
+	}
+	if err != nil {
+		// Resuming is best effort; download without it
+		tflog.Warn(ctx, "Failed to record partial download", map[string]interface{}{
+			"error": err.Error(),
+		})
+		part.discard()
+		releasePartial(part.key)
+		return resp, nil, nil
+	}
+	return resp, part, nil
+}
+
+// claimRecorded claims the partial download key of url in dir and loads it,
+// returning nil when it is in use or has nothing to resume.
+func claimRecorded(dir, key, url string) *partialDownload {
+	if !claimPartial(key) {
+		return nil
+	}
+	part := &partialDownload{dir: dir, key: key}
+	if !part.load(url) {
+		part.discard()
+		releasePartial(key)
+		return nil
+	}
+	return part
+}
+
+// requestResumed requests url, asking for the rest of part if there is one.
+// It returns part when the response continues it; otherwise part is released,
+// and discarded unless it belongs to other content than the server now has,
+// and the response carries the whole download.
+func requestResumed(ctx context.Context, url string, part *partialDownload) (*http.Response, *partialDownload, error) {
+	resp, err := requestImage(ctx, url, part)
+	if err != nil {
+		if part != nil {
+			releasePartial(part.key)
+		}
+		return nil, nil, err
+	}
+	if part == nil || part.continuedBy(resp) {
+		return resp, part, nil
+	}
+
+	if resp.StatusCode == http.StatusOK && contentValidator(resp) != part.state.validator() {
+		// Kept in case the URL serves its content again
+		tflog.Info(ctx, "Partial download is of other content, starting over", map[string]interface{}{
+			"url": redactURL(url),
+		})
+	} else {
+		// The server ignored the range or cannot serve it
+		tflog.Info(ctx, "Discarding partial download that cannot be resumed", map[string]interface{}{
+			"url": redactURL(url),
+		})
+		part.discard()
+	}
+	releasePartial(part.key)
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		if resp, err = requestImage(ctx, url, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+	return resp, nil, nil
+}
+
+// requestImage sends the GET for url, asking for the rest of part when it has
+// bytes on disk.
+func requestImage(ctx context.Context, url string, part *partialDownload) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if part != nil && part.offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", part.offset))
+		req.Header.Set("If-Range", part.state.validator())
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent:
+		return resp, nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		if part != nil && part.offset > 0 {
+			return resp, nil
+		}
+	}
+	resp.Body.Close()
+	return nil, fmt.Errorf("download failed with status: %d", resp.StatusCode)
+}
+
+// resumable reports whether a download could be resumed with a Range request
+// validated by If-Range.
+func resumable(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK || resp.Uncompressed || resp.Header.Get("Accept-Ranges") != "bytes" {
+		return false
+	}
+	state := partialState{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+	return state.validator() != ""
+}
+
+// continuedBy reports whether resp carries the rest of the partial download:
+// a 206 starting at the offset, of the same total size and entity tag.
+func (p *partialDownload) continuedBy(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusPartialContent {
+		return false
+	}
+	if etag := resp.Header.Get("ETag"); etag != "" && p.state.ETag != "" && etag != p.state.ETag {
+		return false
+	}
+	start, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+	return ok && start == p.offset && (p.state.Size < 0 || total < 0 || total == p.state.Size)
+}
+
+// parseContentRange parses "bytes start-end/total", where total may be "*".
+func parseContentRange(value string) (start, total int64, ok bool) {
+	spec, found := strings.CutPrefix(value, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	byteRange, size, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, false
+	}
+	first, _, found := strings.Cut(byteRange, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	total = -1
+	if size != "*" {
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, total, true
+}
+
+// load reads the recorded partial download of url, reporting whether there
+// are bytes to resume.
+func (p *partialDownload) load(url string) bool {
+	data, err := os.ReadFile(p.path(".json"))
+	if err != nil || json.Unmarshal(data, &p.state) != nil {
+		return false
+	}
+	if p.state.URL != url || p.state.validator() == "" || p.key != partialKey(url, p.state.validator()) {
+		return false
+	}
+	stat, err := os.Stat(p.path(".part"))
+	if err != nil || stat.Size() == 0 || (p.state.Size >= 0 && stat.Size() >= p.state.Size) {
+		return false
+	}
+	p.offset = stat.Size()
+	return true
+}
+
+// open records the state and opens the part file for appending; flag
+// os.O_TRUNC starts it over.
+func (p *partialDownload) open(flag int) error {
+	if err := os.MkdirAll(p.dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(p.state)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(p.path(".json"), data, 0644); err != nil {
+		return err
+	}
+	p.file, err = os.OpenFile(p.path(".part"), os.O_RDWR|os.O_CREATE|os.O_APPEND|flag, 0644)
+	return err
+}
+
+// discard removes the partial download so it starts from zero.
+func (p *partialDownload) discard() {
+	os.Remove(p.path(".part"))
+	os.Remove(p.path(".json"))
+	p.offset = 0
+	p.state = partialState{}
+}
+
+// discardOthers removes the partial downloads of other content from the same
+// URL, which a completed download supersedes. Those in progress are left.
+func (p *partialDownload) discardOthers() {
+	for _, key := range partialsOf(p.dir, p.state.URL) {
+		if key == p.key || !claimPartial(key) {
+			continue
+		}
+		other := &partialDownload{dir: p.dir, key: key}
+		other.discard()
+		releasePartial(key)
+	}
+}
+
+// reader returns the whole download: the bytes on disk followed by body,
+// which is appended to the part file as it is read.
+func (p *partialDownload) reader(body io.Reader) io.Reader {
+	return io.MultiReader(
+		io.NewSectionReader(p.file, 0, p.offset),
+		io.TeeReader(&serverReader{r: body, err: &p.bodyErr}, p.file),
+	)
+}
+
+// finish closes the part file once the download ends with *err. A complete
+// download is removed or moved to keepAs; an interrupted one is kept to be
+// resumed, unless it failed for a reason other than the server connection.
+func (p *partialDownload) finish(err *error) {
+	defer releasePartial(p.key)
+	closeErr := p.file.Close()
+	if *err == nil && closeErr == nil {
+		p.discardOthers()
+	}
+
+	switch {
+	case *err == nil && closeErr == nil && p.keepAs != "":
+		if renameErr := os.Rename(p.path(".part"), p.keepAs); renameErr != nil {
+			*err = fmt.Errorf("failed to keep compressed download: %w", renameErr)
+			p.discard()
+			return
+		}
+		os.Remove(p.path(".json"))
+	case *err == nil:
+		p.discard()
+	case p.bodyErr != nil && closeErr == nil:
+		// Keep the bytes received before the connection failed
+	default:
+		p.discard()
+	}
+}
+
+// serverReader records the error of reading a response body, which tells an
+// interrupted download from one the pipeline rejected.
+type serverReader struct {
+	r   io.Reader
+	err *error
+}
+
+func (r *serverReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	// Readers return io.EOF itself at the end; a wrapped EOF is a failure
+	if err != nil && err != io.EOF {
+		*r.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,155 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Defaults applied to RetryPolicy fields left at their zero value.
+const (
+	DefaultRetryMaxAttempts    = 3
+	DefaultRetryInitialBackoff = 1 * time.Second
+	DefaultRetryMaxBackoff     = 30 * time.Second
+)
+
+// RetryPolicy controls how calls to the BMC are retried after transient
+// failures such as 5xx responses or dropped connections.
+type RetryPolicy struct {
+	MaxAttempts    int           // Total attempts including the first; 1 disables retries
+	InitialBackoff time.Duration // Delay before the first retry
+	MaxBackoff     time.Duration // Upper bound for the exponentially growing delay
+}
+
+// withDefaults fills unset fields with the package defaults.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultRetryMaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = DefaultRetryInitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultRetryMaxBackoff
+	}
+	if p.MaxBackoff < p.InitialBackoff {
+		p.MaxBackoff = p.InitialBackoff
+	}
+	return p
+}
+
+// backoff returns the delay before retry number attempt (1-based).
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// IsTransient reports whether err is worth retrying: server-side 5xx and 429
+// responses, timeouts, and refused, reset or truncated connections.
+// Authentication failures, 4xx responses and cancellations are not transient.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError ||
+			statusErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// isUnsent reports whether err shows that a request never reached the BMC
+// because the connection could not be established. Only such failures are
+// safe to retry for requests that must not run twice.
+func isUnsent(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// withRetry runs fn according to the client's retry policy, logging every
+// retried attempt through tflog.
+func withRetry[T any](ctx context.Context, c *Client, operation string, fn func(context.Context) (T, error)) (T, error) {
+	return retryIf(ctx, c, operation, IsTransient, fn)
+}
+
+// retryIf is withRetry with retryable deciding which errors are retried.
+func retryIf[T any](ctx context.Context, c *Client, operation string, retryable func(error) bool, fn func(context.Context) (T, error)) (T, error) {
+	policy := c.retryPolicy
+
+	var result T
+	var err error
+	for attempt := 1; ; attempt++ {
+		result, err = fn(ctx)
+		if err == nil || attempt >= policy.MaxAttempts || !retryable(err) || ctx.Err() != nil {
+			return result, err
+		}
+
+		delay := policy.backoff(attempt)
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+			attrAttempt.Int(attempt),
+			attribute.String("error", err.Error()),
+		))
+		tflog.Warn(ctx, "Transient BMC error, retrying", map[string]interface{}{
+			"operation":    operation,
+			"attempt":      attempt,
+			"max_attempts": policy.MaxAttempts,
+			"backoff":      delay.String(),
+			"error":        err.Error(),
+		})
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+	}
+}
+
+// retryCall is withRetry for operations that return only an error.
+func retryCall(ctx context.Context, c *Client, operation string, fn func(context.Context) error) error {
+	_, err := withRetry(ctx, c, operation, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
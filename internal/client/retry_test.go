@@ -0,0 +1,102 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"503", &StatusError{StatusCode: 503}, true},
+		{"502 wrapped", fmt.Errorf("flash: %w", &StatusError{StatusCode: 502}), true},
+		{"429", &StatusError{StatusCode: 429}, true},
+		{"404", &StatusError{StatusCode: 404}, false},
+		{"connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{"connection refused", fmt.Errorf("dial: %w", syscall.ECONNREFUSED), true},
+		{"unexpected EOF", fmt.Errorf("read body: %w", io.ErrUnexpectedEOF), true},
+		{"cancelled", fmt.Errorf("send: %w", context.Canceled), false},
+		{"auth", errors.New("authentication failed: invalid credentials"), false},
+		{"host key", &HostKeyError{Host: "bmc:22"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.want {
+				t.Errorf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsUnsent(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"connection refused", fmt.Errorf("post: %w", syscall.ECONNREFUSED), true},
+		{"dial timeout", &net.OpError{Op: "dial", Err: errors.New("i/o timeout")}, true},
+		{"read timeout", &net.OpError{Op: "read", Err: errors.New("i/o timeout")}, false},
+		{"connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), false},
+		{"EOF", fmt.Errorf("post: %w", io.EOF), false},
+		{"503", &StatusError{StatusCode: 503}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUnsent(tt.err); got != tt.want {
+				t.Errorf("isUnsent(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}.withDefaults()
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := policy.backoff(i + 1); got != w {
+			t.Errorf("backoff(%d) = %s, want %s", i+1, got, w)
+		}
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	c := &Client{retryPolicy: RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}}
+
+	calls := 0
+	err := retryCall(context.Background(), c, "test", func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return &StatusError{StatusCode: 503}
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("transient errors: got err=%v after %d calls, want success after 3", err, calls)
+	}
+
+	calls = 0
+	err = retryCall(context.Background(), c, "test", func(context.Context) error {
+		calls++
+		return &StatusError{StatusCode: 400}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("permanent error: got err=%v after %d calls, want failure after 1", err, calls)
+	}
+}
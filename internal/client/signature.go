@@ -0,0 +1,148 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Signature schemes, as reported on the checksum.fetch span.
+const (
+	signatureOpenPGP  = "openpgp"
+	signatureMinisign = "minisign"
+)
+
+const minisignComment = "untrusted comment:"
+
+// verifySignature checks that signature, an OpenPGP detached signature
+// (armored or binary) or a minisign signature, signs data with one of
+// trustedKeys. Keys of the other scheme are ignored. It returns the scheme
+// of the signature; failures wrap ErrSignatureInvalid.
+func verifySignature(data, signature []byte, trustedKeys []string) (string, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(signature), []byte(minisignComment)) {
+		return signatureMinisign, verifyMinisign(data, signature, trustedKeys)
+	}
+	return signatureOpenPGP, verifyOpenPGP(data, signature, trustedKeys)
+}
+
+func verifyOpenPGP(data, signature []byte, trustedKeys []string) error {
+	var keyring openpgp.EntityList
+	for _, key := range trustedKeys {
+		if !strings.Contains(key, "-----BEGIN PGP PUBLIC KEY BLOCK-----") {
+			continue
+		}
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key))
+		if err != nil {
+			return fmt.Errorf("failed to read OpenPGP key: %w", err)
+		}
+		keyring = append(keyring, entities...)
+	}
+	if len(keyring) == 0 {
+		return fmt.Errorf("%w: signature is OpenPGP but trusted_keys holds no armored OpenPGP public key", ErrSignatureInvalid)
+	}
+
+	check := openpgp.CheckDetachedSignature
+	if bytes.Contains(signature, []byte("-----BEGIN PGP SIGNATURE-----")) {
+		check = openpgp.CheckArmoredDetachedSignature
+	}
+	if _, err := check(keyring, bytes.NewReader(data), bytes.NewReader(signature), nil); err != nil {
+		return fmt.Errorf("%w: %s", ErrSignatureInvalid, err)
+	}
+	return nil
+}
+
+// minisignKey is a minisign public key: "Ed", then the key ID and the
+// Ed25519 key.
+type minisignKey struct {
+	id  [8]byte
+	key ed25519.PublicKey
+}
+
+// parseMinisignKey parses a public key as minisign prints it, with or
+// without its comment line. It returns false for anything else.
+func parseMinisignKey(s string) (minisignKey, bool) {
+	var encoded string
+	for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, minisignComment) {
+			encoded = line
+			break
+		}
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != "Ed" {
+		return minisignKey{}, false
+	}
+	var key minisignKey
+	copy(key.id[:], raw[2:10])
+	key.key = ed25519.PublicKey(raw[10:])
+	return key, true
+}
+
+// verifyMinisign checks a minisign signature file: a comment line, the
+// signature of data (prehashed with BLAKE2b-512 for the "ED" algorithm), the
+// trusted comment and the global signature binding the two.
+func verifyMinisign(data, signature []byte, trustedKeys []string) error {
+	lines := strings.Split(strings.TrimSpace(string(signature)), "\n")
+	if len(lines) < 4 {
+		return fmt.Errorf("%w: malformed minisign signature", ErrSignatureInvalid)
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(raw) != 2+8+ed25519.SignatureSize {
+		return fmt.Errorf("%w: malformed minisign signature", ErrSignatureInvalid)
+	}
+	algorithm, keyID, sig := string(raw[:2]), raw[2:10], raw[10:]
+	trustedComment, ok := strings.CutPrefix(strings.TrimRight(lines[2], "\r"), "trusted comment: ")
+	if !ok {
+		return fmt.Errorf("%w: minisign signature has no trusted comment", ErrSignatureInvalid)
+	}
+	globalSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(globalSig) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: malformed minisign global signature", ErrSignatureInvalid)
+	}
+
+	message := data
+	switch algorithm {
+	case "Ed":
+	case "ED":
+		sum := blake2b.Sum512(data)
+		message = sum[:]
+	default:
+		return fmt.Errorf("%w: unsupported minisign algorithm %q", ErrSignatureInvalid, algorithm)
+	}
+
+	var found bool
+	for _, s := range trustedKeys {
+		key, ok := parseMinisignKey(s)
+		if !ok || !bytes.Equal(key.id[:], keyID) {
+			continue
+		}
+		found = true
+		if ed25519.Verify(key.key, message, sig) &&
+			ed25519.Verify(key.key, append(append([]byte{}, sig...), trustedComment...), globalSig) {
+			return nil
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: minisign key %X is not in trusted_keys", ErrSignatureInvalid, reverse(keyID))
+	}
+	return fmt.Errorf("%w: minisign signature does not match", ErrSignatureInvalid)
+}
+
+// reverse returns b reversed: minisign stores key IDs little-endian but
+// prints them big-endian.
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
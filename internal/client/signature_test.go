@@ -0,0 +1,108 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// replaceLine returns signature with line i replaced by line.
+func replaceLine(signature []byte, i int, line string) []byte {
+	lines := strings.Split(string(signature), "\n")
+	lines[i] = line
+	return []byte(strings.Join(lines, "\n"))
+}
+
+func TestVerifySignature(t *testing.T) {
+	data := []byte("checksums\n")
+	signature, key := minisignSign(t, data, false)
+	legacySignature, legacyKey := minisignSign(t, data, true)
+	_, otherKey := minisignSign(t, data, false)
+	lines := strings.Split(string(signature), "\n")
+
+	// The same key ID and signature with another algorithm tag
+	raw, _ := base64.StdEncoding.DecodeString(lines[1])
+	unknownAlgorithm := replaceLine(signature, 1, base64.StdEncoding.EncodeToString(append([]byte("Xx"), raw[2:]...)))
+
+	// A valid signature whose global signature does not cover the trusted comment
+	badGlobal := replaceLine(signature, 2, "trusted comment: edited after signing")
+
+	releaseKey := string(readTestdata(t, "release-key.asc"))
+	sums := readTestdata(t, "SHA256SUMS")
+
+	tests := []struct {
+		name       string
+		data       []byte
+		signature  []byte
+		keys       []string
+		wantScheme string
+		wantErr    string // Empty for a valid signature
+	}{
+		{"minisign", data, signature, []string{key}, signatureMinisign, ""},
+		{"legacy minisign", data, legacySignature, []string{legacyKey}, signatureMinisign, ""},
+		{"minisign key without comment", data, signature, []string{strings.Split(key, "\n")[1]}, signatureMinisign, ""},
+		{"openpgp", sums, readTestdata(t, "SHA256SUMS.asc"), []string{releaseKey}, signatureOpenPGP, ""},
+		{"truncated minisign", data, []byte(strings.Join(lines[:2], "\n")), []string{key}, signatureMinisign, "malformed minisign signature"},
+		{"minisign signature not base64", data, replaceLine(signature, 1, "not base64!"), []string{key}, signatureMinisign, "malformed minisign signature"},
+		{"no trusted comment", data, replaceLine(signature, 2, "comment: x"), []string{key}, signatureMinisign, "no trusted comment"},
+		{"unknown algorithm", data, unknownAlgorithm, []string{key}, signatureMinisign, "unsupported minisign algorithm"},
+		{"bad global signature", data, badGlobal, []string{key}, signatureMinisign, "does not match"},
+		{"tampered data", []byte("checksums!\n"), signature, []string{key}, signatureMinisign, "does not match"},
+		{"untrusted minisign key", data, signature, []string{otherKey, releaseKey}, signatureMinisign, "not in trusted_keys"},
+		{"openpgp with only minisign keys", sums, readTestdata(t, "SHA256SUMS.asc"), []string{key, otherKey}, signatureOpenPGP, "no armored OpenPGP public key"},
+		{"openpgp garbage", sums, []byte("not a signature"), []string{releaseKey}, signatureOpenPGP, "invalid signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme, err := verifySignature(tt.data, tt.signature, tt.keys)
+			if scheme != tt.wantScheme {
+				t.Errorf("scheme = %q, want %q", scheme, tt.wantScheme)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("verifySignature: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrSignatureInvalid) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want ErrSignatureInvalid mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseMinisignKey(t *testing.T) {
+	_, key := minisignSign(t, []byte("data"), false)
+	encoded := strings.Split(key, "\n")[1]
+	raw, _ := base64.StdEncoding.DecodeString(encoded)
+
+	tests := []struct {
+		name string
+		key  string
+		want bool
+	}{
+		{"with comment", key, true},
+		{"bare", encoded, true},
+		{"surrounding whitespace", "\n  " + encoded + "  \n", true},
+		{"openpgp", string(readTestdata(t, "release-key.asc")), false},
+		{"truncated", encoded[:len(encoded)-8], false},
+		{"wrong algorithm", base64.StdEncoding.EncodeToString(append([]byte("ED"), raw[2:]...)), false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, ok := parseMinisignKey(tt.key)
+			if ok != tt.want {
+				t.Fatalf("parseMinisignKey ok = %v, want %v", ok, tt.want)
+			}
+			if ok && (!bytes.Equal(parsed.id[:], raw[2:10]) || !bytes.Equal(parsed.key, raw[10:])) {
+				t.Errorf("parsed key %x/%x, want %x", parsed.id, parsed.key, raw[2:])
+			}
+		})
+	}
+}
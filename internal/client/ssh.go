@@ -0,0 +1,338 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tpi "github.com/davidroman0O/tpi/client"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// sshDialTimeout bounds the TCP connect and SSH handshake with the BMC.
+const sshDialTimeout = 10 * time.Second
+
+// sshAuthMethods builds the SSH authentication chain.
+// Methods are offered in the same order OpenSSH uses: agent keys, then the
+// configured private key, then password. The returned cleanup function
+// releases the agent connection and must be called once the handshake is done.
+func (c *Client) sshAuthMethods() ([]ssh.AuthMethod, func(), error) {
+	var methods []ssh.AuthMethod
+	cleanup := func() {}
+
+	if c.SSHUseAgent {
+		socket := os.Getenv("SSH_AUTH_SOCK")
+		if socket == "" {
+			return nil, cleanup, fmt.Errorf("ssh_use_agent is set but SSH_AUTH_SOCK is not")
+		}
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			return nil, cleanup, fmt.Errorf("failed to connect to SSH agent: %w", err)
+		}
+		cleanup = func() { conn.Close() }
+		methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+	}
+
+	keyPEM, err := c.sshPrivateKeyPEM()
+	if err != nil {
+		cleanup()
+		return nil, func() {}, err
+	}
+	if keyPEM != nil {
+		signer, err := ssh.ParsePrivateKey(keyPEM)
+		if err != nil {
+			cleanup()
+			var missing *ssh.PassphraseMissingError
+			if errors.As(err, &missing) {
+				return nil, func() {}, fmt.Errorf("SSH private key is passphrase-protected; load it into an SSH agent and set ssh_use_agent instead")
+			}
+			return nil, func() {}, fmt.Errorf("failed to parse SSH private key: %w", err)
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+
+	if c.SSHPassword != "" {
+		methods = append(methods, ssh.Password(c.SSHPassword))
+	}
+
+	if len(methods) == 0 {
+		cleanup()
+		return nil, func() {}, fmt.Errorf("no SSH authentication method configured")
+	}
+
+	return methods, cleanup, nil
+}
+
+// sshPrivateKeyPEM returns the configured private key, reading it from disk
+// when only a path was given. It returns nil when no key is configured.
+func (c *Client) sshPrivateKeyPEM() ([]byte, error) {
+	if c.SSHPrivateKey != "" {
+		return []byte(c.SSHPrivateKey), nil
+	}
+	if c.SSHPrivateKeyPath == "" {
+		return nil, nil
+	}
+
+	keyPath, err := expandHome(c.SSHPrivateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH private key: %w", err)
+	}
+	return data, nil
+}
+
+// dialSSH opens an authenticated SSH connection to the BMC. ctx bounds the
+// connect and handshake only. Operations use the pooled connection from
+// sshConn instead of dialing their own.
+func (c *Client) dialSSH(ctx context.Context) (*ssh.Client, error) {
+	methods, cleanup, err := c.sshAuthMethods()
+	if err != nil {
+		return nil, tagError(ErrSSHAuthentication, err)
+	}
+	defer cleanup()
+
+	hostKeyCallback, err := c.sshHostKeyCallback()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &ssh.ClientConfig{
+		User:            c.SSHUser,
+		Auth:            methods,
+		HostKeyCallback: hostKeyCallback,
+	}
+
+	// Host may carry the API port; SSH always uses SSHPort
+	host := c.Host
+	if h, _, err := net.SplitHostPort(c.Host); err == nil {
+		host = h
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(c.SSHPort))
+
+	dialer := net.Dialer{Timeout: sshDialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		if ctx.Err() == nil {
+			err = tagError(ErrUnreachable, err)
+		}
+		return nil, fmt.Errorf("failed to connect to SSH server: %w", err)
+	}
+
+	// The handshake is bounded by both the dial timeout and ctx
+	netConn.SetDeadline(time.Now().Add(sshDialTimeout))
+	stop := closeOnCancel(ctx, netConn)
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, addr, config)
+	cancelled := !stop()
+	if err != nil || cancelled {
+		netConn.Close()
+		var hostKeyErr *HostKeyError
+		if errors.As(err, &hostKeyErr) {
+			return nil, hostKeyErr
+		}
+		if cancelled {
+			return nil, fmt.Errorf("failed to connect to SSH server: %w", context.Cause(ctx))
+		}
+		// x/crypto/ssh reports rejected credentials only through its message
+		if strings.Contains(err.Error(), "unable to authenticate") {
+			err = tagError(ErrSSHAuthentication, err)
+		}
+		return nil, fmt.Errorf("failed to connect to SSH server: %w", err)
+	}
+	netConn.SetDeadline(time.Time{})
+
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// closeOnCancel closes conn when ctx is done, which makes any blocked read
+// or write on it fail; operations pass their session or SFTP client, so a
+// cancelled operation leaves the pooled connection open. The returned stop
+// function detaches conn again and reports whether it did so before ctx was
+// done.
+func closeOnCancel(ctx context.Context, conn io.Closer) func() bool {
+	return context.AfterFunc(ctx, func() { conn.Close() })
+}
+
+// cancelledError replaces err with the cause of ctx when ctx ended while
+// the operation was running, so callers see why it was aborted rather than
+// a closed-connection error.
+func cancelledError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("SSH operation aborted: %w", context.Cause(ctx))
+	}
+	return err
+}
+
+// UploadFile uploads a local file to the BMC via SFTP.
+func (c *Client) UploadFile(localPath, remotePath string) error {
+	return c.UploadFileContext(c.ctx, localPath, remotePath)
+}
+
+// UploadFileContext is UploadFile with a context that aborts the transfer.
+func (c *Client) UploadFileContext(ctx context.Context, localPath, remotePath string) (err error) {
+	ctx, span := startSpan(ctx, "client.UploadFile")
+	defer func() { endSpan(span, err) }()
+
+	if err := c.checkWritable("UploadFile"); err != nil {
+		return err
+	}
+	defer c.audit.begin(ctx, "UploadFile", 0, map[string]interface{}{
+		"local_path":  localPath,
+		"remote_path": remotePath,
+	}).end(&err)
+	return retryCall(ctx, c, "UploadFile", func(ctx context.Context) error {
+		return cancelledError(ctx, c.uploadFile(ctx, localPath, remotePath))
+	})
+}
+
+// uploadFile performs a single SFTP upload attempt.
+func (c *Client) uploadFile(ctx context.Context, localPath, remotePath string) (err error) {
+	localFile, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open local file: %w", err)
+	}
+	defer localFile.Close()
+
+	stat, err := localFile.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to get file info: %w", err)
+	}
+	if stat.IsDir() {
+		return fmt.Errorf("cannot upload a directory, only files are supported")
+	}
+	setSpanAttributes(ctx, attrBytes.Int64(stat.Size()))
+
+	conn, err := c.sshConn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to establish SSH connection: %w", err)
+	}
+
+	sftpClient, err := sftp.NewClient(conn)
+	if err != nil {
+		c.discardSSH(ctx, conn)
+		return fmt.Errorf("failed to create SFTP client: %w", err)
+	}
+	defer sftpClient.Close()
+	defer closeOnCancel(ctx, sftpClient)()
+
+	// Remote paths are always POSIX paths, regardless of the local OS
+	remoteDir := path.Dir(remotePath)
+	if remoteDir != "." && remoteDir != "/" {
+		if err := sftpClient.MkdirAll(remoteDir); err != nil {
+			return fmt.Errorf("failed to create remote directory: %w", err)
+		}
+	}
+
+	// Write to a temporary name and rename it into place once complete, so
+	// an interrupted upload never leaves a truncated file at remotePath,
+	// where the BMC cache would take it for a complete image
+	partPath := remotePath + ".part"
+	remoteFile, err := sftpClient.Create(partPath)
+	if err != nil {
+		return fmt.Errorf("failed to create remote file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			remoteFile.Close()
+			sftpClient.Remove(partPath)
+		}
+	}()
+
+	if err := sftpClient.Chmod(partPath, stat.Mode()); err != nil {
+		return fmt.Errorf("failed to set file permissions: %w", err)
+	}
+
+	if _, err := io.Copy(remoteFile, localFile); err != nil {
+		return fmt.Errorf("failed to copy file content: %w", spaceError(err))
+	}
+	if err := remoteFile.Close(); err != nil {
+		return fmt.Errorf("failed to copy file content: %w", spaceError(err))
+	}
+
+	if err := sftpClient.PosixRename(partPath, remotePath); err != nil {
+		// Without the posix-rename extension, renaming over an existing
+		// file fails, so remove it first
+		sftpClient.Remove(remotePath)
+		if err := sftpClient.Rename(partPath, remotePath); err != nil {
+			return fmt.Errorf("failed to move uploaded file into place: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListDirectory lists files in a directory on the BMC.
+func (c *Client) ListDirectory(remotePath string) ([]tpi.FileInfo, error) {
+	return c.ListDirectoryContext(c.ctx, remotePath)
+}
+
+// ListDirectoryContext is ListDirectory with a context that aborts the listing.
+func (c *Client) ListDirectoryContext(ctx context.Context, remotePath string) (_ []tpi.FileInfo, err error) {
+	ctx, span := startSpan(ctx, "client.ListDirectory")
+	defer func() { endSpan(span, err) }()
+
+	return withRetry(ctx, c, "ListDirectory", func(ctx context.Context) ([]tpi.FileInfo, error) {
+		files, err := c.listDirectory(ctx, remotePath)
+		return files, cancelledError(ctx, err)
+	})
+}
+
+// listDirectory performs a single SFTP directory listing attempt.
+func (c *Client) listDirectory(ctx context.Context, remotePath string) ([]tpi.FileInfo, error) {
+	conn, err := c.sshConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to establish SSH connection: %w", err)
+	}
+
+	sftpClient, err := sftp.NewClient(conn)
+	if err != nil {
+		c.discardSSH(ctx, conn)
+		return nil, fmt.Errorf("failed to create SFTP client: %w", err)
+	}
+	defer sftpClient.Close()
+	defer closeOnCancel(ctx, sftpClient)()
+
+	entries, err := sftpClient.ReadDir(remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	files := make([]tpi.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		files = append(files, tpi.FileInfo{
+			Name:    entry.Name(),
+			Size:    entry.Size(),
+			Mode:    entry.Mode(),
+			ModTime: entry.ModTime(),
+			IsDir:   entry.IsDir(),
+		})
+	}
+
+	return files, nil
+}
+
+// expandHome replaces a leading "~" in path with the user's home directory.
+func expandHome(p string) (string, error) {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, strings.TrimPrefix(p, "~")), nil
+}
@@ -0,0 +1,138 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"golang.org/x/crypto/ssh"
+)
+
+// sshKeepaliveTimeout bounds the keepalive that checks a pooled connection
+// before it is reused.
+const sshKeepaliveTimeout = 5 * time.Second
+
+// sshPool holds the SSH connection shared by every SSH operation of a Client.
+// The handshake costs the BMC seconds, so the connection is opened on first
+// use and kept; each operation runs in its own session on it.
+type sshPool struct {
+	lock chan struct{} // Held while the connection is checked or replaced
+	conn *ssh.Client
+}
+
+func newSSHPool() *sshPool {
+	return &sshPool{lock: make(chan struct{}, 1)}
+}
+
+// acquire takes the pool lock, giving up when ctx is done first. A handshake
+// holds the lock until it completes, so waiting must stay cancellable.
+func (p *sshPool) acquire(ctx context.Context) error {
+	// A free lock is taken even when ctx is already done
+	select {
+	case p.lock <- struct{}{}:
+		return nil
+	default:
+	}
+
+	select {
+	case p.lock <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+func (p *sshPool) release() {
+	<-p.lock
+}
+
+// sshConn returns the pooled SSH connection, dialing one when there is none
+// yet or the current one no longer answers a keepalive. Callers must not
+// close it; use discardSSH when it fails.
+func (c *Client) sshConn(ctx context.Context) (*ssh.Client, error) {
+	if err := c.ssh.acquire(ctx); err != nil {
+		return nil, fmt.Errorf("failed to connect to SSH server: %w", err)
+	}
+	defer c.ssh.release()
+
+	if conn := c.ssh.conn; conn != nil {
+		err := keepalive(conn)
+		if err == nil {
+			return conn, nil
+		}
+		tflog.Debug(ctx, "Pooled SSH connection is unhealthy, reconnecting", map[string]interface{}{
+			"error": err.Error(),
+		})
+		conn.Close()
+		c.ssh.conn = nil
+	}
+
+	conn, err := c.dialSSH(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.ssh.conn = conn
+	return conn, nil
+}
+
+// discardSSH closes conn and drops it from the pool, so the next operation
+// dials a new connection. It does nothing to the pool when conn was already
+// replaced. When ctx ends before the pool is free, conn is still closed and
+// the next keepalive check drops it.
+func (c *Client) discardSSH(ctx context.Context, conn *ssh.Client) {
+	defer conn.Close()
+	if c.ssh.acquire(ctx) != nil {
+		return
+	}
+	defer c.ssh.release()
+
+	if c.ssh.conn == conn {
+		c.ssh.conn = nil
+	}
+}
+
+// Close closes the pooled SSH connection. The client remains usable; the
+// next SSH operation opens a new connection.
+func (c *Client) Close() error {
+	return c.CloseContext(c.ctx)
+}
+
+// CloseContext is Close with a context that bounds the wait for an SSH
+// operation still connecting, such as a handshake that hangs.
+func (c *Client) CloseContext(ctx context.Context) error {
+	if err := c.ssh.acquire(ctx); err != nil {
+		return fmt.Errorf("failed to close SSH connection: %w", err)
+	}
+	defer c.ssh.release()
+
+	if c.ssh.conn == nil {
+		return nil
+	}
+	err := c.ssh.conn.Close()
+	c.ssh.conn = nil
+	return err
+}
+
+// keepalive sends an OpenSSH keepalive request and waits for the reply.
+// Servers that do not know the request still answer it with a failure,
+// which proves the connection is alive.
+func keepalive(conn *ssh.Client) error {
+	result := make(chan error, 1)
+	go func() {
+		_, _, err := conn.SendRequest("keepalive@openssh.com", true, nil)
+		result <- err
+	}()
+
+	timer := time.NewTimer(sshKeepaliveTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-result:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("no keepalive reply within %s", sshKeepaliveTimeout)
+	}
+}
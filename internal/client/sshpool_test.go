@@ -0,0 +1,31 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCloseContextGivesUpOnHeldPool(t *testing.T) {
+	c := &Client{ctx: context.Background(), ssh: newSSHPool()}
+
+	// An SSH operation is stuck in its handshake
+	if err := c.ssh.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c.CloseContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("CloseContext = %v, want the deadline error", err)
+	}
+
+	c.ssh.release()
+	if err := c.CloseContext(ctx); err != nil {
+		t.Errorf("CloseContext on a free pool with a done context = %v, want nil", err)
+	}
+}
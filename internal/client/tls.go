@@ -0,0 +1,133 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+)
+
+const (
+	// SchemeHTTP talks to the BMC API over plain HTTP (older firmware).
+	SchemeHTTP = "http"
+	// SchemeHTTPS talks to the BMC API over TLS.
+	SchemeHTTPS = "https"
+)
+
+// TLSConfig describes how the BMC API connection is secured.
+type TLSConfig struct {
+	CACertPEM          string // PEM bundle of trusted CAs or pinned server certificates
+	InsecureSkipVerify bool   // Accept any server certificate
+	ClientCertPEM      string // PEM client certificate for mutual TLS
+	ClientKeyPEM       string // PEM private key matching ClientCertPEM
+}
+
+// buildTLSConfig translates a TLSConfig into a crypto/tls configuration for host.
+//
+// Certificates in CACertPEM act both as trust anchors and as pins: a server
+// presenting exactly one of those certificates is accepted even when its
+// subject does not match the host, which is the common case for the
+// self-signed certificate generated by BMC firmware and addressed by IP.
+func buildTLSConfig(host string, cfg TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if cfg.ClientCertPEM != "" || cfg.ClientKeyPEM != "" {
+		cert, err := tls.X509KeyPair([]byte(cfg.ClientCertPEM), []byte(cfg.ClientKeyPEM))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if cfg.InsecureSkipVerify {
+		// A CA bundle would be ignored, leaving the connection unverified
+		// although a trusted certificate was configured
+		if cfg.CACertPEM != "" {
+			return nil, fmt.Errorf("a CA certificate cannot be combined with skipping certificate verification")
+		}
+		tlsConfig.InsecureSkipVerify = true
+		return tlsConfig, nil
+	}
+
+	if cfg.CACertPEM == "" {
+		// System roots with standard hostname verification
+		return tlsConfig, nil
+	}
+
+	pinned, err := parseCertificates([]byte(cfg.CACertPEM))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+	}
+	roots := x509.NewCertPool()
+	for _, cert := range pinned {
+		roots.AddCert(cert)
+	}
+
+	serverName := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		serverName = h
+	}
+
+	// Verification is done in VerifyConnection so pinned certificates can
+	// bypass the hostname check; the standard verifier is disabled.
+	tlsConfig.InsecureSkipVerify = true
+	tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return fmt.Errorf("BMC presented no TLS certificate")
+		}
+		leaf := state.PeerCertificates[0]
+
+		for _, cert := range pinned {
+			if bytes.Equal(cert.Raw, leaf.Raw) {
+				return nil
+			}
+		}
+
+		intermediates := x509.NewCertPool()
+		for _, cert := range state.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := leaf.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			DNSName:       serverName,
+		})
+		if err != nil {
+			return fmt.Errorf("BMC TLS certificate is not trusted by ca_cert_pem: %w", err)
+		}
+		return nil
+	}
+
+	return tlsConfig, nil
+}
+
+// parseCertificates decodes every CERTIFICATE block in data.
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM certificates found")
+	}
+	return certs, nil
+}
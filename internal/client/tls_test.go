@@ -0,0 +1,90 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildTLSConfigPinning(t *testing.T) {
+	bmc := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer bmc.Close()
+
+	pinned := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: bmc.Certificate().Raw}))
+	host := strings.TrimPrefix(bmc.URL, "https://")
+
+	tests := []struct {
+		name    string
+		cfg     TLSConfig
+		url     string
+		wantErr bool
+	}{
+		{name: "pinned certificate", cfg: TLSConfig{CACertPEM: pinned}, url: bmc.URL},
+		{name: "insecure", cfg: TLSConfig{InsecureSkipVerify: true}, url: bmc.URL},
+		{name: "system roots", cfg: TLSConfig{}, url: bmc.URL, wantErr: true},
+		{name: "wrong pin", cfg: TLSConfig{CACertPEM: selfSignedPEM(t)}, url: bmc.URL, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, err := buildTLSConfig(host, tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+			resp, err := httpClient.Get(tt.url)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildTLSConfigInsecureWithCA(t *testing.T) {
+	cfg := TLSConfig{CACertPEM: selfSignedPEM(t), InsecureSkipVerify: true}
+	if _, err := buildTLSConfig("bmc", cfg); err == nil {
+		t.Error("expected an error for a CA bundle that skipping verification would ignore")
+	}
+}
+
+func TestBuildTLSConfigInvalidPEM(t *testing.T) {
+	if _, err := buildTLSConfig("bmc", TLSConfig{CACertPEM: "not a certificate"}); err == nil {
+		t.Error("expected an error for an invalid CA bundle")
+	}
+}
+
+// selfSignedPEM returns a freshly generated, unrelated self-signed certificate.
+func selfSignedPEM(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "turingpi"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
@@ -0,0 +1,53 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans of this package. Spans go to the global
+// tracer provider, which is a no-op unless trace export is configured.
+const tracerName = "github.com/davidroman0O/terraform-provider-turingpi/internal/client"
+
+// Span attributes.
+const (
+	attrNode          = attribute.Key("turingpi.node")
+	attrBytes         = attribute.Key("turingpi.bytes")
+	attrBytesOut      = attribute.Key("turingpi.bytes_out")
+	attrCompression   = attribute.Key("turingpi.compression")
+	attrCacheLocation = attribute.Key("turingpi.cache.location")
+	attrCacheHit      = attribute.Key("turingpi.cache.hit")
+	attrAttempt       = attribute.Key("turingpi.attempt")
+	attrResumeOffset  = attribute.Key("turingpi.resume_offset")
+	attrConnections   = attribute.Key("turingpi.connections")
+	attrArchiveMember = attribute.Key("turingpi.archive.member")
+	attrSignature     = attribute.Key("turingpi.signature")
+	attrURL           = attribute.Key("url.full")
+)
+
+// startSpan starts a span named name as a child of the span in ctx.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records err, if any, on span and ends it. It is meant to be
+// deferred with the operation's named error result.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// setSpanAttributes adds attrs to the span in ctx, if any.
+func setSpanAttributes(ctx context.Context, attrs ...attribute.KeyValue) {
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+}
@@ -0,0 +1,113 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider that records every ended span for
+// the duration of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+// spanAttributes returns the attributes of the ended spans by name.
+func spanAttributes(recorder *tracetest.SpanRecorder) map[string]map[attribute.Key]attribute.Value {
+	spans := make(map[string]map[attribute.Key]attribute.Value)
+	for _, span := range recorder.Ended() {
+		attrs := make(map[attribute.Key]attribute.Value)
+		for _, kv := range span.Attributes() {
+			attrs[kv.Key] = kv.Value
+		}
+		spans[span.Name()] = attrs
+	}
+	return spans
+}
+
+func TestDownloadImageSpans(t *testing.T) {
+	recorder := recordSpans(t)
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(bytes.Repeat([]byte("turingpi"), 1024))
+	gz.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(compressed.Bytes())
+	}))
+	defer server.Close()
+
+	_, err := DownloadImage(context.Background(), server.URL+"/image.img.gz", &DownloadOptions{DestDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := spanAttributes(recorder)
+	for _, name := range []string{"download.image", "download.fetch"} {
+		if _, ok := spans[name]; !ok {
+			t.Errorf("no %s span; got %v", name, spans)
+		}
+	}
+	fetch := spans["download.fetch"]
+	if got := fetch[attrBytes].AsInt64(); got != int64(compressed.Len()) {
+		t.Errorf("download.fetch bytes = %d, want %d", got, compressed.Len())
+	}
+	if fetch[attrCompression].AsString() != "gz" || fetch[attrBytesOut].AsInt64() != 8192 {
+		t.Errorf("download.fetch attributes = %v", fetch)
+	}
+
+	// Every span of the download belongs to the same trace
+	traceID := recorder.Ended()[0].SpanContext().TraceID()
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID() != traceID {
+			t.Errorf("span %s is in a different trace", span.Name())
+		}
+	}
+}
+
+func TestClientCallSpans(t *testing.T) {
+	recorder := recordSpans(t)
+
+	bmc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/bmc/authenticate" {
+			w.Write([]byte(`{"id":"token"}`))
+			return
+		}
+		w.Write([]byte(`{"response":[{"result":"ok"}]}`))
+	}))
+	defer bmc.Close()
+
+	c, err := NewClient(context.Background(), Config{
+		Host:   bmc.Listener.Addr().String(),
+		Scheme: SchemeHTTP,
+		Retry:  RetryPolicy{MaxAttempts: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.PowerOn(3); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := spanAttributes(recorder)
+	power, ok := spans["client.PowerOn"]
+	if !ok || power[attrNode].AsInt64() != 3 {
+		t.Errorf("client.PowerOn span = %v, %v; want node 3", power, ok)
+	}
+}
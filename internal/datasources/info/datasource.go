@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"github.com/davidroman0O/terraform-provider-turingpi/internal/client"
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/diagnostics"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/types"
@@ -22,7 +23,7 @@ func NewInfoDataSource() datasource.DataSource {
 
 // InfoDataSource defines the data source implementation.
 type InfoDataSource struct {
-	client *client.Client
+	client client.BMC
 }
 
 // InfoDataSourceModel describes the data source data model.
@@ -88,11 +89,11 @@ func (d *InfoDataSource) Configure(ctx context.Context, req datasource.Configure
 		return
 	}
 
-	client, ok := req.ProviderData.(*client.Client)
+	client, ok := req.ProviderData.(client.BMC)
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *client.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected client.BMC, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 		return
 	}
@@ -110,22 +111,24 @@ func (d *InfoDataSource) Read(ctx context.Context, req datasource.ReadRequest, r
 	}
 
 	// Get basic info
-	info, err := d.client.Info()
+	info, err := d.client.InfoContext(ctx)
 	if err != nil {
-		resp.Diagnostics.AddError(
+		resp.Diagnostics.Append(diagnostics.ClientError(
 			"Unable to Read BMC Info",
-			fmt.Sprintf("Could not read BMC info: %s", err.Error()),
-		)
+			"Could not read BMC info",
+			err,
+		))
 		return
 	}
 
 	// Get detailed about info
-	about, err := d.client.About()
+	about, err := d.client.AboutContext(ctx)
 	if err != nil {
-		resp.Diagnostics.AddError(
+		resp.Diagnostics.Append(diagnostics.ClientError(
 			"Unable to Read BMC About",
-			fmt.Sprintf("Could not read BMC about info: %s", err.Error()),
-		)
+			"Could not read BMC about info",
+			err,
+		))
 		return
 	}
 
@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"github.com/davidroman0O/terraform-provider-turingpi/internal/client"
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/diagnostics"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/types"
@@ -22,7 +23,7 @@ func NewPowerStatusDataSource() datasource.DataSource {
 
 // PowerStatusDataSource defines the data source implementation.
 type PowerStatusDataSource struct {
-	client *client.Client
+	client client.BMC
 }
 
 // PowerStatusDataSourceModel describes the data source data model.
@@ -72,11 +73,11 @@ func (d *PowerStatusDataSource) Configure(ctx context.Context, req datasource.Co
 		return
 	}
 
-	client, ok := req.ProviderData.(*client.Client)
+	client, ok := req.ProviderData.(client.BMC)
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *client.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected client.BMC, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 		return
 	}
@@ -92,12 +93,13 @@ func (d *PowerStatusDataSource) Read(ctx context.Context, req datasource.ReadReq
 		return
 	}
 
-	status, err := d.client.PowerStatus()
+	status, err := d.client.PowerStatusContext(ctx)
 	if err != nil {
-		resp.Diagnostics.AddError(
+		resp.Diagnostics.Append(diagnostics.ClientError(
 			"Unable to Read Power Status",
-			fmt.Sprintf("Could not read power status: %s", err.Error()),
-		)
+			"Could not read power status",
+			err,
+		))
 		return
 	}
 
@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"github.com/davidroman0O/terraform-provider-turingpi/internal/client"
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/diagnostics"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/types"
@@ -22,7 +23,7 @@ func NewUsbStatusDataSource() datasource.DataSource {
 
 // UsbStatusDataSource defines the data source implementation.
 type UsbStatusDataSource struct {
-	client *client.Client
+	client client.BMC
 }
 
 // UsbStatusDataSourceModel describes the data source data model.
@@ -67,11 +68,11 @@ func (d *UsbStatusDataSource) Configure(ctx context.Context, req datasource.Conf
 		return
 	}
 
-	client, ok := req.ProviderData.(*client.Client)
+	client, ok := req.ProviderData.(client.BMC)
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *client.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected client.BMC, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 		return
 	}
@@ -87,12 +88,13 @@ func (d *UsbStatusDataSource) Read(ctx context.Context, req datasource.ReadReque
 		return
 	}
 
-	status, err := d.client.UsbGetStatus()
+	status, err := d.client.UsbGetStatusContext(ctx)
 	if err != nil {
-		resp.Diagnostics.AddError(
+		resp.Diagnostics.Append(diagnostics.ClientError(
 			"Unable to Read USB Status",
-			fmt.Sprintf("Could not read USB status: %s", err.Error()),
-		)
+			"Could not read USB status",
+			err,
+		))
 		return
 	}
 
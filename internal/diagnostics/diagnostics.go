@@ -0,0 +1,119 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+// Package diagnostics turns client errors into Terraform diagnostics that say
+// what went wrong and what to do about it.
+package diagnostics
+
+import (
+	"errors"
+
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/client"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+)
+
+// kind pairs a class of client error with the summary and remediation shown
+// for it.
+type kind struct {
+	match   func(error) bool
+	summary string
+	hint    string
+}
+
+// is matches errors wrapping target.
+func is(target error) func(error) bool {
+	return func(err error) bool { return errors.Is(err, target) }
+}
+
+// kinds is checked in order; the first match wins. Credential and host key
+// failures come first because retrying cannot fix them.
+var kinds = []kind{
+	{
+		match:   is(client.ErrReadOnly),
+		summary: "Provider Is Read-Only",
+		hint:    "The provider is configured with read_only = true. Remove read_only from the provider configuration to apply changes.",
+	},
+	{
+		match:   is(client.ErrAuthentication),
+		summary: "BMC Authentication Failed",
+		hint: "The BMC rejected the provider credentials. Check username and password, or the token and whether it has expired. " +
+			"These can also be set with TURINGPI_USERNAME, TURINGPI_PASSWORD and TURINGPI_TOKEN.",
+	},
+	{
+		match:   is(client.ErrSSHAuthentication),
+		summary: "BMC SSH Authentication Failed",
+		hint: "The BMC rejected the SSH login. Check ssh_user and the configured ssh_password, ssh_private_key, " +
+			"ssh_private_key_path or ssh_use_agent.",
+	},
+	{
+		match:   client.IsHostKeyError,
+		summary: "BMC SSH Host Key Verification Failed",
+		hint: "The SSH host key presented by the BMC does not match ssh_host_key_fingerprint or ssh_known_hosts_file. " +
+			"If the BMC was reinstalled, verify its new key and update the configuration.",
+	},
+	{
+		match:   is(client.ErrSignatureInvalid),
+		summary: "Checksum File Signature Invalid",
+		hint: "The checksum file at checksum_url is not signed by any of trusted_keys, so the mirror may have been tampered with. " +
+			"Check that signature_url belongs to checksum_url and that trusted_keys holds the publisher's current key.",
+	},
+	{
+		match:   is(client.ErrChecksumMismatch),
+		summary: "Image Checksum Mismatch",
+		hint: "The image does not match its SHA256. Check the sha256 attribute or the checksum_url file against the image publisher, " +
+			"and delete any cached copy under ~/.cache/terraform-provider-turingpi or /tmp/tpi-cache on the BMC before retrying.",
+	},
+	{
+		match:   is(client.ErrInsufficientSpace),
+		summary: "Insufficient Space",
+		hint: "A disk filled up while writing the image. Free space locally and under /tmp/tpi-cache on the BMC, " +
+			"or use a different cache setting.",
+	},
+	{
+		match:   is(client.ErrFlashInProgress),
+		summary: "Flash In Progress",
+		hint: "The BMC is already flashing a node and can only run one flash at a time. Wait for it to finish, " +
+			"or raise locks.board_timeout and locks.node_timeout so operations queue behind it for longer.",
+	},
+	{
+		match:   is(client.ErrNodeBusy),
+		summary: "Node Busy",
+		hint: "Another operation on the same node did not finish in time. Wait for it to finish, " +
+			"or raise locks.node_timeout so operations queue for longer.",
+	},
+	{
+		match:   is(client.ErrUnreachable),
+		summary: "BMC Unreachable",
+		hint: "The BMC did not answer. Check that it is powered and on the network, that host is correct, " +
+			"and that ssh_port is open when caching on the BMC.",
+	},
+}
+
+// ClientError returns an error diagnostic for err, a failure of action.
+// Errors of a known kind get a summary naming the problem and a remediation
+// hint; anything else keeps summary. The detail always starts with
+// "action: err".
+func ClientError(summary, action string, err error) diag.Diagnostic {
+	detail := action + ": " + err.Error()
+	for _, k := range kinds {
+		if k.match(err) {
+			return diag.NewErrorDiagnostic(k.summary, detail+"\n\n"+k.hint)
+		}
+	}
+	return diag.NewErrorDiagnostic(summary, detail)
+}
+
+// ReadOnlyPlan returns an error diagnostic when bmc is read-only and req
+// would create, update or delete a resourceType resource. A plan that keeps
+// the state as it is passes.
+func ReadOnlyPlan(bmc client.BMC, req resource.ModifyPlanRequest, resourceType string) diag.Diagnostics {
+	if bmc == nil || !bmc.ReadOnly() || req.Plan.Raw.Equal(req.State.Raw) {
+		return nil
+	}
+	return diag.Diagnostics{diag.NewErrorDiagnostic(
+		"Provider Is Read-Only",
+		"The turingpi provider is configured with read_only = true, so this plan may not create, update or delete "+resourceType+" resources. "+
+			"Remove read_only from the provider configuration to apply changes.",
+	)}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package diagnostics
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/client"
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/client/fake"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestClientError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		summary string
+		hint    string
+	}{
+		{"unclassified", errors.New("boom"), "Error Reading Power Status", ""},
+		{"read-only", fmt.Errorf("%w: PowerOn is not allowed", client.ErrReadOnly), "Provider Is Read-Only", "read_only"},
+		{"unauthorized", fmt.Errorf("%w: invalid credentials", client.ErrAuthentication), "BMC Authentication Failed", "TURINGPI_PASSWORD"},
+		{"ssh auth", fmt.Errorf("dial: %w", client.ErrSSHAuthentication), "BMC SSH Authentication Failed", "ssh_user"},
+		{"host key", &client.HostKeyError{Host: "bmc:22"}, "BMC SSH Host Key Verification Failed", "ssh_host_key_fingerprint"},
+		{"checksum", fmt.Errorf("%w: expected SHA256 a, got b", client.ErrChecksumMismatch), "Image Checksum Mismatch", "/tmp/tpi-cache"},
+		{"signature", fmt.Errorf("SHA256SUMS: %w", client.ErrSignatureInvalid), "Checksum File Signature Invalid", "trusted_keys"},
+		{"space", fmt.Errorf("copy: %w", client.ErrInsufficientSpace), "Insufficient Space", "Free space"},
+		{"flash in progress", &client.LockTimeoutError{Resource: "board", Operation: "UsbSetHost", Holder: "FlashNode", Waited: time.Second}, "Flash In Progress", "locks.board_timeout"},
+		{"node busy", &client.LockTimeoutError{Resource: "node 1", Operation: "PowerOn", Holder: "PowerOff", Waited: time.Second}, "Node Busy", "locks.node_timeout"},
+		{"unreachable", fmt.Errorf("request failed: %w", client.ErrUnreachable), "BMC Unreachable", "powered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := ClientError("Error Reading Power Status", "Could not read power status", tt.err)
+			if d.Summary() != tt.summary {
+				t.Errorf("summary = %q, want %q", d.Summary(), tt.summary)
+			}
+			want := "Could not read power status: " + tt.err.Error()
+			if !strings.HasPrefix(d.Detail(), want) {
+				t.Errorf("detail = %q, want prefix %q", d.Detail(), want)
+			}
+			if tt.hint == "" && d.Detail() != want {
+				t.Errorf("detail = %q, want no hint", d.Detail())
+			}
+			if !strings.Contains(d.Detail(), tt.hint) {
+				t.Errorf("detail = %q, want hint mentioning %q", d.Detail(), tt.hint)
+			}
+		})
+	}
+}
+
+func TestReadOnlyPlan(t *testing.T) {
+	s := schema.Schema{Attributes: map[string]schema.Attribute{"node": schema.Int64Attribute{Required: true}}}
+	objectType := tftypes.Object{AttributeTypes: map[string]tftypes.Type{"node": tftypes.Number}}
+	empty := tftypes.NewValue(objectType, nil)
+	node1 := tftypes.NewValue(objectType, map[string]tftypes.Value{"node": tftypes.NewValue(tftypes.Number, 1)})
+	request := func(plan, state tftypes.Value) resource.ModifyPlanRequest {
+		return resource.ModifyPlanRequest{
+			Plan:  tfsdk.Plan{Schema: s, Raw: plan},
+			State: tfsdk.State{Schema: s, Raw: state},
+		}
+	}
+
+	readOnly := fake.New(fake.WithReadOnly())
+	if diags := ReadOnlyPlan(readOnly, request(node1, empty), "turingpi_node_power"); !diags.HasError() ||
+		!strings.Contains(diags[0].Detail(), "turingpi_node_power") {
+		t.Errorf("create while read-only: got %v, want an error naming the resource type", diags)
+	}
+	if diags := ReadOnlyPlan(readOnly, request(node1, node1), "turingpi_node_power"); diags.HasError() {
+		t.Errorf("unchanged plan while read-only: got %v", diags)
+	}
+	if diags := ReadOnlyPlan(fake.New(), request(node1, empty), "turingpi_node_power"); diags.HasError() {
+		t.Errorf("create while writable: got %v", diags)
+	}
+	if diags := ReadOnlyPlan(nil, request(node1, empty), "turingpi_node_power"); diags.HasError() {
+		t.Errorf("unconfigured provider: got %v", diags)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package bmc_session
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/client"
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/diagnostics"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ ephemeral.EphemeralResource = &BMCSessionEphemeralResource{}
+var _ ephemeral.EphemeralResourceWithConfigure = &BMCSessionEphemeralResource{}
+
+func NewBMCSessionEphemeralResource() ephemeral.EphemeralResource {
+	return &BMCSessionEphemeralResource{}
+}
+
+// BMCSessionEphemeralResource defines the ephemeral resource implementation.
+type BMCSessionEphemeralResource struct {
+	client client.BMC
+}
+
+// BMCSessionEphemeralResourceModel describes the ephemeral resource data model.
+type BMCSessionEphemeralResourceModel struct {
+	Host  types.String `tfsdk:"host"`
+	Token types.String `tfsdk:"token"`
+}
+
+func (r *BMCSessionEphemeralResource) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_bmc_session"
+}
+
+func (r *BMCSessionEphemeralResource) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Opens a BMC API session and exposes its bearer token without storing it in state.",
+		MarkdownDescription: `Opens a BMC API session and exposes its bearer token without storing it in state.
+
+Requires Terraform 1.10 or later. The token can be passed to the ` + "`token`" + ` attribute of
+another provider configuration, so only one configuration ever holds the BMC password.
+
+## Example Usage
+
+` + "```hcl" + `
+ephemeral "turingpi_bmc_session" "bmc" {}
+
+provider "turingpi" {
+  alias = "session"
+  host  = "192.168.1.90"
+  token = ephemeral.turingpi_bmc_session.bmc.token
+}
+` + "```" + `
+`,
+		Attributes: map[string]schema.Attribute{
+			"host": schema.StringAttribute{
+				Description: "BMC the session was opened on.",
+				Computed:    true,
+			},
+			"token": schema.StringAttribute{
+				Description: "Bearer token of the session.",
+				Computed:    true,
+				Sensitive:   true,
+			},
+		},
+	}
+}
+
+func (r *BMCSessionEphemeralResource) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(client.BMC)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Ephemeral Resource Configure Type",
+			fmt.Sprintf("Expected client.BMC, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+func (r *BMCSessionEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	if r.client == nil {
+		resp.Diagnostics.AddError(
+			"Provider Not Configured",
+			"The turingpi provider must be configured with known values to open a BMC session.",
+		)
+		return
+	}
+
+	token, err := r.client.NewSessionContext(ctx)
+	if err != nil {
+		resp.Diagnostics.Append(diagnostics.ClientError(
+			"Unable to Open BMC Session",
+			"Could not log in to the BMC",
+			err,
+		))
+		return
+	}
+
+	tflog.Debug(ctx, "Opened BMC session", map[string]interface{}{
+		"host": r.client.Address(),
+	})
+
+	data := BMCSessionEphemeralResourceModel{
+		Host:  types.StringValue(r.client.Address()),
+		Token: types.StringValue(token),
+	}
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+}
@@ -0,0 +1,52 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package functions
+
+import (
+	"context"
+
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/client"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ function.Function = &DetectCompressionFunction{}
+
+func NewDetectCompressionFunction() function.Function {
+	return &DetectCompressionFunction{}
+}
+
+// DetectCompressionFunction reports the compression implied by an image URL's
+// extension.
+type DetectCompressionFunction struct{}
+
+func (f *DetectCompressionFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "detect_compression"
+}
+
+func (f *DetectCompressionFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Detect the compression of an image URL from its extension",
+		Description:         "Returns the compression implied by the extension of an image URL: \"xz\", \"gz\", \"zip\", \"zst\", \"bz2\", \"lz4\", \"tar\", or \"\" when it does not look compressed. Only the last extension counts, so .tar.gz is reported as \"gz\". turingpi_node_flash recognizes images by their first bytes and only falls back to this hint when they match no known format; its compression attribute overrides both.",
+		MarkdownDescription: "Returns the compression implied by the extension of an image URL: `xz`, `gz`, `zip`, `zst`, `bz2`, `lz4`, `tar`, or `\"\"` when it does not look compressed. Only the last extension counts, so `.tar.gz` is reported as `gz`. `turingpi_node_flash` recognizes images by their first bytes and only falls back to this hint when they match no known format; its `compression` attribute overrides both.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:        "url",
+				Description: "Image URL or file name.",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *DetectCompressionFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var url string
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &url))
+	if resp.Error != nil {
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, client.DetectCompression(url)))
+}
@@ -0,0 +1,35 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package functions
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestDetectCompressionRun(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://example.com/ubuntu.img.xz", "xz"},
+		{"https://example.com/ubuntu.img.xz?download=1", "xz"},
+		{"https://example.com/ubuntu.img.zst?token=a.gz", "zst"},
+		{"https://example.com/ubuntu.tar.gz#sha256", "gz"},
+		{"https://example.com/ubuntu.img?format=xz", ""},
+		{"ubuntu.img", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, err := run(t, NewDetectCompressionFunction(), types.StringValue(tt.url))
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("detect_compression(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
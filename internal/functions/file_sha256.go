@@ -0,0 +1,58 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package functions
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/client"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ function.Function = &FileSHA256Function{}
+
+func NewFileSHA256Function() function.Function {
+	return &FileSHA256Function{}
+}
+
+// FileSHA256Function computes the SHA256 checksum of a local file.
+type FileSHA256Function struct{}
+
+func (f *FileSHA256Function) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "file_sha256"
+}
+
+func (f *FileSHA256Function) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Compute the SHA256 checksum of a local file",
+		Description:         "Returns the lowercase hex-encoded SHA256 checksum of the file at path, in the format expected by the sha256 attribute of turingpi_node_flash.",
+		MarkdownDescription: "Returns the lowercase hex-encoded SHA256 checksum of the file at `path`, in the format expected by the `sha256` attribute of `turingpi_node_flash`.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:        "path",
+				Description: "Path to the file to hash.",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *FileSHA256Function) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var path string
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &path))
+	if resp.Error != nil {
+		return
+	}
+
+	sum, err := client.CalculateFileSHA256(path)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, fmt.Sprintf("Unable to hash %s: %s", path, err))
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, sum))
+}
@@ -0,0 +1,40 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package functions
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestFileSHA256Run(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "image.img")
+	if err := os.WriteFile(path, []byte("turingpi"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := run(t, NewFileSHA256Function(), types.StringValue(path))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if want := "d5a301457e031f43e52722c9440f89a94d59eee373a569919e30e0dbaee419b5"; got != want {
+		t.Errorf("file_sha256 = %q, want %q", got, want)
+	}
+}
+
+func TestFileSHA256RunMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.img")
+
+	_, err := run(t, NewFileSHA256Function(), types.StringValue(path))
+	if err == nil || !strings.Contains(err.Text, "Unable to hash "+path) {
+		t.Fatalf("err = %v, want one naming %s", err, path)
+	}
+	if err.FunctionArgument == nil || *err.FunctionArgument != 0 {
+		t.Errorf("error blames argument %v, want 0", err.FunctionArgument)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package functions
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ function.Function = &NodeIDFunction{}
+
+func NewNodeIDFunction() function.Function {
+	return &NodeIDFunction{}
+}
+
+// NodeIDFunction builds the ID of a per-node resource, as used for import.
+type NodeIDFunction struct{}
+
+func (f *NodeIDFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "node_id"
+}
+
+func (f *NodeIDFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Build the ID of a node resource",
+		Description:         "Returns the ID used by turingpi_node_power (\"power\") or turingpi_node_usb (\"usb\") for a node, e.g. node-1-power. Useful in import blocks.",
+		MarkdownDescription: "Returns the ID used by `turingpi_node_power` (`power`) or `turingpi_node_usb` (`usb`) for a node, e.g. `node-1-power`. Useful in `import` blocks.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:        "kind",
+				Description: "Resource kind: \"power\" or \"usb\".",
+				Validators: []function.StringParameterValidator{
+					stringvalidator.OneOf("power", "usb"),
+				},
+			},
+			function.Int64Parameter{
+				Name:        "node",
+				Description: "Node number (1-4).",
+				Validators: []function.Int64ParameterValidator{
+					int64validator.Between(1, 4),
+				},
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *NodeIDFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var kind string
+	var node int64
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &kind, &node))
+	if resp.Error != nil {
+		return
+	}
+
+	// Terraform runs the parameter validators first; this guards other callers
+	if node < 1 || node > 4 {
+		resp.Error = function.NewArgumentFuncError(1, fmt.Sprintf("Node must be between 1 and 4, got %d", node))
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, fmt.Sprintf("node-%d-%s", node, kind)))
+}
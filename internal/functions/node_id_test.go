@@ -0,0 +1,60 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package functions
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// run calls f.Run with args and returns its result and error.
+func run(t *testing.T, f function.Function, args ...attr.Value) (string, *function.FuncError) {
+	t.Helper()
+
+	resp := &function.RunResponse{Result: function.NewResultData(types.StringUnknown())}
+	f.Run(context.Background(), function.RunRequest{Arguments: function.NewArgumentsData(args)}, resp)
+	if resp.Error != nil {
+		return "", resp.Error
+	}
+	return resp.Result.Value().(types.String).ValueString(), nil
+}
+
+func TestNodeIDRun(t *testing.T) {
+	tests := []struct {
+		kind    string
+		node    int64
+		want    string
+		wantErr string
+	}{
+		{kind: "power", node: 1, want: "node-1-power"},
+		{kind: "usb", node: 4, want: "node-4-usb"},
+		{kind: "power", node: 0, wantErr: "got 0"},
+		{kind: "usb", node: 5, wantErr: "got 5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want+tt.wantErr, func(t *testing.T) {
+			got, err := run(t, NewNodeIDFunction(), types.StringValue(tt.kind), types.Int64Value(tt.node))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Text, tt.wantErr) {
+					t.Fatalf("err = %v, want one mentioning %q", err, tt.wantErr)
+				}
+				if err.FunctionArgument == nil || *err.FunctionArgument != 1 {
+					t.Errorf("error blames argument %v, want 1", err.FunctionArgument)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("node_id = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
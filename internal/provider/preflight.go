@@ -0,0 +1,79 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/client"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+)
+
+// runPreflight checks the BMC connection and records one attribute-level
+// error per failed check, pointing at the setting most likely at fault.
+// tokenOnly tells it that the API is authenticated by a token alone.
+func runPreflight(ctx context.Context, c *client.Client, config TuringPiProviderModel, tokenOnly bool, resp *provider.ConfigureResponse) {
+	// SSH is only needed for BMC caching, so only probe it when that is the
+	// default or SSH was configured explicitly
+	checkSSH := c.DefaultCache == client.CacheLocationBMC ||
+		!config.SSHPrivateKey.IsNull() ||
+		!config.SSHPrivateKeyPath.IsNull() ||
+		!config.SSHUseAgent.IsNull() ||
+		!config.SSHKnownHostsFile.IsNull() ||
+		!config.SSHHostKeyFingerprint.IsNull()
+
+	for _, failure := range c.PreflightContext(ctx, checkSSH) {
+		attrPath, summary, detail := preflightDiagnostic(c, failure, tokenOnly)
+		resp.Diagnostics.AddAttributeError(attrPath, summary, detail+"\n\nError: "+failure.Error())
+	}
+}
+
+// preflightDiagnostic returns the attribute, summary and guidance for a
+// failed preflight check.
+func preflightDiagnostic(c *client.Client, failure *client.PreflightError, tokenOnly bool) (path.Path, string, string) {
+	switch failure.Check {
+	case client.PreflightTLS:
+		return path.Root("ca_cert_pem"), "BMC Certificate Not Trusted",
+			fmt.Sprintf("The TLS certificate presented by %s did not verify against ca_cert_pem. "+
+				"Check that ca_cert_pem contains the BMC certificate or the CA that issued it.", c.Host)
+	case client.PreflightAuth:
+		if tokenOnly {
+			return path.Root("token"), "BMC Authentication Failed",
+				fmt.Sprintf("The BMC at %s rejected the configured session token. It may have expired.", c.Host)
+		}
+		return path.Root("password"), "BMC Authentication Failed",
+			fmt.Sprintf("The BMC at %s rejected the configured username and password.", c.Host)
+	case client.PreflightAPIVersion:
+		return path.Root("host"), "Unsupported BMC Firmware",
+			fmt.Sprintf("The BMC at %s runs a firmware API version this provider does not support. "+
+				"Upgrade the BMC firmware or use a provider release that supports it.", c.Host)
+	case client.PreflightSSHHostKey:
+		attr := "ssh_known_hosts_file"
+		if c.SSHHostKeyFingerprint != "" {
+			attr = "ssh_host_key_fingerprint"
+		}
+		return path.Root(attr), "BMC SSH Host Key Verification Failed",
+			fmt.Sprintf("The SSH host key presented by %s does not match the configured %s.", c.Host, attr)
+	case client.PreflightSSHAuth:
+		attr := "ssh_password"
+		switch {
+		case c.SSHPrivateKeyPath != "":
+			attr = "ssh_private_key_path"
+		case c.SSHPrivateKey != "":
+			attr = "ssh_private_key"
+		case c.SSHUseAgent:
+			attr = "ssh_use_agent"
+		}
+		return path.Root(attr), "BMC SSH Authentication Failed",
+			fmt.Sprintf("The BMC at %s rejected SSH login as %q. BMC caching needs working SSH credentials.", c.Host, c.SSHUser)
+	case client.PreflightSSH:
+		return path.Root("ssh_port"), "BMC SSH Unreachable",
+			fmt.Sprintf("Could not open an SSH connection to %s on port %d. BMC caching needs SSH access.", c.Host, c.SSHPort)
+	default:
+		return path.Root("host"), "BMC Unreachable",
+			fmt.Sprintf("Could not reach the BMC API at %s. Check the host, the scheme and that the BMC is powered and on the network.", c.Host)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/client"
+	"gopkg.in/yaml.v3"
+)
+
+// Profile is a named set of connection settings from the provider config file.
+// Every field is optional; values set in the provider block take precedence.
+type Profile struct {
+	Host     string `yaml:"host"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+
+	SSHUser               string `yaml:"ssh_user"`
+	SSHPassword           string `yaml:"ssh_password"`
+	SSHPort               int    `yaml:"ssh_port"`
+	SSHPrivateKeyPath     string `yaml:"ssh_private_key_path"`
+	SSHUseAgent           *bool  `yaml:"ssh_use_agent"`
+	SSHKnownHostsFile     string `yaml:"ssh_known_hosts_file"`
+	SSHHostKeyFingerprint string `yaml:"ssh_host_key_fingerprint"`
+
+	// Cache is the default cache strategy for turingpi_node_flash resources
+	// that do not set one.
+	Cache string `yaml:"cache"`
+}
+
+// profileFile is the layout of the provider config file.
+type profileFile struct {
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// profileConfigPath returns the location of the provider config file:
+// TURINGPI_CONFIG_FILE if set, otherwise $XDG_CONFIG_HOME/turingpi/config.yaml,
+// falling back to ~/.config/turingpi/config.yaml.
+func profileConfigPath() (string, error) {
+	if p := os.Getenv("TURINGPI_CONFIG_FILE"); p != "" {
+		return p, nil
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "turingpi", "config.yaml"), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".config", "turingpi", "config.yaml"), nil
+}
+
+// loadProfile reads the named profile from the provider config file.
+func loadProfile(name string) (*Profile, error) {
+	configPath, err := profileConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(configPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("profile %q requested but config file %s does not exist", name, configPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var file profileFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+	}
+
+	profile, ok := file.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(file.Profiles))
+		for n := range file.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("profile %q not found in %s (available: %s)", name, configPath, strings.Join(names, ", "))
+	}
+
+	switch profile.Cache {
+	case "", client.CacheLocationLocal, client.CacheLocationBMC, client.CacheLocationNone:
+	default:
+		return nil, fmt.Errorf("profile %q: invalid cache %q (must be %q, %q or %q)",
+			name, profile.Cache, client.CacheLocationLocal, client.CacheLocationBMC, client.CacheLocationNone)
+	}
+
+	return &profile, nil
+}
@@ -0,0 +1,75 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadProfile(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("TURINGPI_CONFIG_FILE", configPath)
+
+	if _, err := loadProfile("lab"); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("missing file: got %v", err)
+	}
+
+	config := `
+profiles:
+  lab:
+    host: 10.0.0.5
+    password: lab-secret
+    ssh_use_agent: true
+    cache: bmc
+  prod:
+    host: bmc.prod.example.com
+    ssh_port: 2222
+`
+	if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	lab, err := loadProfile("lab")
+	if err != nil {
+		t.Fatalf("loadProfile(lab): %v", err)
+	}
+	if lab.Host != "10.0.0.5" || lab.Password != "lab-secret" || lab.Cache != "bmc" {
+		t.Errorf("lab profile = %+v", lab)
+	}
+	if lab.SSHUseAgent == nil || !*lab.SSHUseAgent {
+		t.Errorf("lab ssh_use_agent = %v, want true", lab.SSHUseAgent)
+	}
+
+	prod, err := loadProfile("prod")
+	if err != nil {
+		t.Fatalf("loadProfile(prod): %v", err)
+	}
+	if prod.SSHPort != 2222 || prod.SSHUseAgent != nil {
+		t.Errorf("prod profile = %+v", prod)
+	}
+
+	if _, err := loadProfile("staging"); err == nil || !strings.Contains(err.Error(), "available: lab, prod") {
+		t.Errorf("unknown profile: got %v", err)
+	}
+}
+
+func TestLoadProfileRejectsInvalidConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("TURINGPI_CONFIG_FILE", configPath)
+
+	for name, config := range map[string]string{
+		"unknown field": "profiles:\n  lab:\n    hostname: 10.0.0.5\n",
+		"invalid cache": "profiles:\n  lab:\n    cache: s3\n",
+	} {
+		if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadProfile("lab"); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -5,25 +5,40 @@ package provider
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
+	"regexp"
+	"sync"
+	"time"
 
 	"github.com/davidroman0O/terraform-provider-turingpi/internal/client"
 	"github.com/davidroman0O/terraform-provider-turingpi/internal/datasources/info"
 	"github.com/davidroman0O/terraform-provider-turingpi/internal/datasources/power_status"
 	"github.com/davidroman0O/terraform-provider-turingpi/internal/datasources/usb_status"
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/ephemeral/bmc_session"
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/functions"
 	"github.com/davidroman0O/terraform-provider-turingpi/internal/resources/node_flash"
 	"github.com/davidroman0O/terraform-provider-turingpi/internal/resources/node_power"
 	"github.com/davidroman0O/terraform-provider-turingpi/internal/resources/node_usb"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/function"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 // Ensure TuringPiProvider satisfies various provider interfaces.
 var _ provider.Provider = &TuringPiProvider{}
+var _ provider.ProviderWithFunctions = &TuringPiProvider{}
+var _ provider.ProviderWithEphemeralResources = &TuringPiProvider{}
 
 // TuringPiProvider defines the provider implementation.
 type TuringPiProvider struct {
@@ -32,12 +47,69 @@ type TuringPiProvider struct {
 
 // TuringPiProviderModel describes the provider data model.
 type TuringPiProviderModel struct {
+	Profile     types.String `tfsdk:"profile"`
 	Host        types.String `tfsdk:"host"`
 	Username    types.String `tfsdk:"username"`
 	Password    types.String `tfsdk:"password"`
+	Token       types.String `tfsdk:"token"`
 	SSHUser     types.String `tfsdk:"ssh_user"`
 	SSHPassword types.String `tfsdk:"ssh_password"`
 	SSHPort     types.Int64  `tfsdk:"ssh_port"`
+
+	SSHPrivateKey     types.String `tfsdk:"ssh_private_key"`
+	SSHPrivateKeyPath types.String `tfsdk:"ssh_private_key_path"`
+	SSHUseAgent       types.Bool   `tfsdk:"ssh_use_agent"`
+
+	SSHKnownHostsFile     types.String `tfsdk:"ssh_known_hosts_file"`
+	SSHHostKeyFingerprint types.String `tfsdk:"ssh_host_key_fingerprint"`
+
+	Scheme             types.String `tfsdk:"scheme"`
+	CACertPEM          types.String `tfsdk:"ca_cert_pem"`
+	InsecureSkipVerify types.Bool   `tfsdk:"insecure_skip_verify"`
+	ClientCertPEM      types.String `tfsdk:"client_cert_pem"`
+	ClientKeyPEM       types.String `tfsdk:"client_key_pem"`
+
+	Preflight    types.Bool   `tfsdk:"preflight"`
+	ReadOnly     types.Bool   `tfsdk:"read_only"`
+	AuditLogPath types.String `tfsdk:"audit_log_path"`
+
+	Retry *RetryModel `tfsdk:"retry"`
+	Locks *LocksModel `tfsdk:"locks"`
+}
+
+// RetryModel describes the retry block.
+type RetryModel struct {
+	MaxAttempts    types.Int64  `tfsdk:"max_attempts"`
+	InitialBackoff types.String `tfsdk:"initial_backoff"`
+	MaxBackoff     types.String `tfsdk:"max_backoff"`
+}
+
+// LocksModel describes the locks block.
+type LocksModel struct {
+	BoardTimeout types.String `tfsdk:"board_timeout"`
+	NodeTimeout  types.String `tfsdk:"node_timeout"`
+}
+
+// configured holds the clients created by Configure, so Shutdown can close
+// their pooled SSH connections when the provider process stops.
+var configured struct {
+	mu      sync.Mutex
+	clients []*client.Client
+}
+
+// Shutdown closes the clients configured by this process. ctx bounds the wait
+// for SSH operations that are still connecting.
+func Shutdown(ctx context.Context) error {
+	configured.mu.Lock()
+	clients := configured.clients
+	configured.clients = nil
+	configured.mu.Unlock()
+
+	var errs []error
+	for _, c := range clients {
+		errs = append(errs, c.CloseContext(ctx))
+	}
+	return errors.Join(errs...)
 }
 
 func New(version string) func() provider.Provider {
@@ -77,6 +149,11 @@ provider "turingpi" {
 ` + "```" + `
 `,
 		Attributes: map[string]schema.Attribute{
+			"profile": schema.StringAttribute{
+				Description:         "Name of a profile in ~/.config/turingpi/config.yaml supplying host, credentials, SSH settings and the default flash cache. Attributes set here override the profile. Can also be set via TURINGPI_PROFILE environment variable.",
+				MarkdownDescription: "Name of a profile in `~/.config/turingpi/config.yaml` supplying host, credentials, SSH settings and the default flash cache. Attributes set here override the profile. Can also be set via `TURINGPI_PROFILE` environment variable.",
+				Optional:            true,
+			},
 			"host": schema.StringAttribute{
 				Description:         "BMC hostname or IP address. Can also be set via TURINGPI_HOST environment variable.",
 				MarkdownDescription: "BMC hostname or IP address. Can also be set via `TURINGPI_HOST` environment variable.",
@@ -93,14 +170,20 @@ provider "turingpi" {
 				Optional:            true,
 				Sensitive:           true,
 			},
+			"token": schema.StringAttribute{
+				Description:         "Bearer token of an existing BMC API session, e.g. from the turingpi_bmc_session ephemeral resource, used instead of logging in. If the BMC rejects it, the provider logs in with username and password only when those are set explicitly. Can also be set via TURINGPI_TOKEN environment variable.",
+				MarkdownDescription: "Bearer token of an existing BMC API session, e.g. from the `turingpi_bmc_session` ephemeral resource, used instead of logging in. If the BMC rejects it, the provider logs in with `username` and `password` only when those are set explicitly. Can also be set via `TURINGPI_TOKEN` environment variable.",
+				Optional:            true,
+				Sensitive:           true,
+			},
 			"ssh_user": schema.StringAttribute{
 				Description:         "SSH username for BMC file operations (used for BMC caching). Defaults to username if not set.",
 				MarkdownDescription: "SSH username for BMC file operations (used for BMC caching). Defaults to `username` if not set.",
 				Optional:            true,
 			},
 			"ssh_password": schema.StringAttribute{
-				Description:         "SSH password for BMC file operations (used for BMC caching). Defaults to password if not set.",
-				MarkdownDescription: "SSH password for BMC file operations (used for BMC caching). Defaults to `password` if not set.",
+				Description:         "SSH password for BMC file operations (used for BMC caching). Defaults to password if not set and no key-based authentication is configured.",
+				MarkdownDescription: "SSH password for BMC file operations (used for BMC caching). Defaults to `password` if not set and no key-based authentication is configured.",
 				Optional:            true,
 				Sensitive:           true,
 			},
@@ -109,6 +192,133 @@ provider "turingpi" {
 				MarkdownDescription: "SSH port for BMC file operations. Default: `22`",
 				Optional:            true,
 			},
+			"ssh_private_key": schema.StringAttribute{
+				Description:         "PEM-encoded private key for SSH public key authentication. Conflicts with ssh_private_key_path.",
+				MarkdownDescription: "PEM-encoded private key for SSH public key authentication. Conflicts with `ssh_private_key_path`.",
+				Optional:            true,
+				Sensitive:           true,
+				Validators: []validator.String{
+					stringvalidator.ConflictsWith(path.MatchRoot("ssh_private_key_path")),
+				},
+			},
+			"ssh_private_key_path": schema.StringAttribute{
+				Description:         "Path to a PEM-encoded private key for SSH public key authentication. A leading ~ is expanded to the home directory.",
+				MarkdownDescription: "Path to a PEM-encoded private key for SSH public key authentication. A leading `~` is expanded to the home directory.",
+				Optional:            true,
+			},
+			"ssh_use_agent": schema.BoolAttribute{
+				Description:         "Authenticate SSH with the keys held by the agent at SSH_AUTH_SOCK. Default: false",
+				MarkdownDescription: "Authenticate SSH with the keys held by the agent at `SSH_AUTH_SOCK`. Default: `false`",
+				Optional:            true,
+			},
+			"ssh_known_hosts_file": schema.StringAttribute{
+				Description:         "OpenSSH known_hosts file used to verify the BMC host key. Connections to unknown or mismatching hosts are refused.",
+				MarkdownDescription: "OpenSSH `known_hosts` file used to verify the BMC host key. Connections to unknown or mismatching hosts are refused.",
+				Optional:            true,
+			},
+			"ssh_host_key_fingerprint": schema.StringAttribute{
+				Description:         "Pinned SHA256 fingerprint of the BMC host key, as printed by ssh-keygen -lf (e.g. SHA256:abc...). Connections presenting any other key are refused.",
+				MarkdownDescription: "Pinned SHA256 fingerprint of the BMC host key, as printed by `ssh-keygen -lf` (e.g. `SHA256:abc...`). Connections presenting any other key are refused.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(
+						regexp.MustCompile(`^(SHA256:)?[A-Za-z0-9+/]{43}=?$`),
+						"must be a SHA256 host key fingerprint such as SHA256:uNiVztksCsDhcc0u9e8BujQXVUpKZIDTMczCvj3tD2s",
+					),
+				},
+			},
+			"scheme": schema.StringAttribute{
+				Description:         "Scheme used to reach the BMC API: http or https. Default: https",
+				MarkdownDescription: "Scheme used to reach the BMC API: `http` or `https`. Default: `https`",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(client.SchemeHTTP, client.SchemeHTTPS),
+				},
+			},
+			"ca_cert_pem": schema.StringAttribute{
+				Description:         "PEM-encoded CA bundle used to verify the BMC API certificate. A self-signed BMC certificate can be given here to pin it; a server presenting exactly that certificate is accepted regardless of hostname.",
+				MarkdownDescription: "PEM-encoded CA bundle used to verify the BMC API certificate. A self-signed BMC certificate can be given here to pin it; a server presenting exactly that certificate is accepted regardless of hostname.",
+				Optional:            true,
+			},
+			"insecure_skip_verify": schema.BoolAttribute{
+				Description:         "Skip verification of the BMC API certificate. Cannot be true when ca_cert_pem is set. Default: true unless ca_cert_pem is set, matching the self-signed certificate shipped with BMC firmware.",
+				MarkdownDescription: "Skip verification of the BMC API certificate. Cannot be `true` when `ca_cert_pem` is set. Default: `true` unless `ca_cert_pem` is set, matching the self-signed certificate shipped with BMC firmware.",
+				Optional:            true,
+			},
+			"client_cert_pem": schema.StringAttribute{
+				Description:         "PEM-encoded client certificate presented to the BMC API for mutual TLS. Requires client_key_pem.",
+				MarkdownDescription: "PEM-encoded client certificate presented to the BMC API for mutual TLS. Requires `client_key_pem`.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.AlsoRequires(path.MatchRoot("client_key_pem")),
+				},
+			},
+			"client_key_pem": schema.StringAttribute{
+				Description:         "PEM-encoded private key for client_cert_pem.",
+				MarkdownDescription: "PEM-encoded private key for `client_cert_pem`.",
+				Optional:            true,
+				Sensitive:           true,
+				Validators: []validator.String{
+					stringvalidator.AlsoRequires(path.MatchRoot("client_cert_pem")),
+				},
+			},
+			"preflight": schema.BoolAttribute{
+				Description:         "Check the BMC connection while configuring the provider: reachability, API credentials, firmware API version and, when BMC caching is used or SSH is configured, SSH access. Failures are reported against the responsible attribute. Default: false",
+				MarkdownDescription: "Check the BMC connection while configuring the provider: reachability, API credentials, firmware API version and, when BMC caching is used or SSH is configured, SSH access. Failures are reported against the responsible attribute. Default: `false`",
+				Optional:            true,
+			},
+			"read_only": schema.BoolAttribute{
+				Description:         "Refuse every operation that changes the BMC or a node. Data sources and refreshes keep working, and any planned create, update or delete of a resource fails at plan time. Default: false",
+				MarkdownDescription: "Refuse every operation that changes the BMC or a node. Data sources and refreshes keep working, and any planned create, update or delete of a resource fails at plan time. Default: `false`",
+				Optional:            true,
+			},
+			"audit_log_path": schema.StringAttribute{
+				Description:         "File to append a JSON line to for every operation that changes the BMC or a node: power, USB, flash, uploads and commands. Each line records the time, BMC host, local and BMC user, Terraform workspace, node, operation, parameters with secrets redacted, duration and outcome. The file is created with mode 0600 if missing.",
+				MarkdownDescription: "File to append a JSON line to for every operation that changes the BMC or a node: power, USB, flash, uploads and commands. Each line records the time, BMC host, local and BMC user, Terraform workspace, node, operation, parameters with secrets redacted, duration and outcome. The file is created with mode `0600` if missing.",
+				Optional:            true,
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"retry": schema.SingleNestedBlock{
+				Description:         "Retry policy for transient BMC failures (5xx responses, timeouts, dropped connections). Other errors fail immediately.",
+				MarkdownDescription: "Retry policy for transient BMC failures (5xx responses, timeouts, dropped connections). Other errors fail immediately.",
+				Attributes: map[string]schema.Attribute{
+					"max_attempts": schema.Int64Attribute{
+						Description:         "Total number of attempts per BMC call, including the first. Set to 1 to disable retries. Default: 3",
+						MarkdownDescription: "Total number of attempts per BMC call, including the first. Set to `1` to disable retries. Default: `3`",
+						Optional:            true,
+						Validators: []validator.Int64{
+							int64validator.AtLeast(1),
+						},
+					},
+					"initial_backoff": schema.StringAttribute{
+						Description:         "Delay before the first retry, as a Go duration. Doubles on every retry. Default: 1s",
+						MarkdownDescription: "Delay before the first retry, as a Go duration. Doubles on every retry. Default: `1s`",
+						Optional:            true,
+					},
+					"max_backoff": schema.StringAttribute{
+						Description:         "Upper bound for the delay between retries, as a Go duration. Default: 30s",
+						MarkdownDescription: "Upper bound for the delay between retries, as a Go duration. Default: `30s`",
+						Optional:            true,
+					},
+				},
+			},
+			"locks": schema.SingleNestedBlock{
+				Description:         "Wait limits for the in-process locks that keep parallel resources from driving the BMC at the same time. Flashes and USB changes share a board-wide lock; power changes lock only their node.",
+				MarkdownDescription: "Wait limits for the in-process locks that keep parallel resources from driving the BMC at the same time. Flashes and USB changes share a board-wide lock; power changes lock only their node.",
+				Attributes: map[string]schema.Attribute{
+					"board_timeout": schema.StringAttribute{
+						Description:         "How long a flash or USB change waits for the board lock, as a Go duration. Default: 3h",
+						MarkdownDescription: "How long a flash or USB change waits for the board lock, as a Go duration. Default: `3h`",
+						Optional:            true,
+					},
+					"node_timeout": schema.StringAttribute{
+						Description:         "How long a power change waits for its node's lock, as a Go duration. Default: 3h",
+						MarkdownDescription: "How long a power change waits for its node's lock, as a Go duration. Default: `3h`",
+						Optional:            true,
+					},
+				},
+			},
 		},
 	}
 }
@@ -122,8 +332,35 @@ func (p *TuringPiProvider) Configure(ctx context.Context, req provider.Configure
 		return
 	}
 
-	// Get host from config or environment
+	// Load the selected profile, if any. An explicitly selected profile wins
+	// over the TURINGPI_* connection variables so switching profiles can
+	// never silently target the board named in the environment.
+	profileName := config.Profile.ValueString()
+	if profileName == "" {
+		profileName = os.Getenv("TURINGPI_PROFILE")
+	}
+	var profile Profile
+	if profileName != "" {
+		loaded, err := loadProfile(profileName)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("profile"),
+				"Unable to Load Turing Pi Profile",
+				err.Error(),
+			)
+			return
+		}
+		profile = *loaded
+		tflog.Debug(ctx, "Using Turing Pi profile", map[string]interface{}{
+			"profile": profileName,
+		})
+	}
+
+	// Get host from config, profile or environment
 	host := config.Host.ValueString()
+	if host == "" {
+		host = profile.Host
+	}
 	if host == "" {
 		host = os.Getenv("TURINGPI_HOST")
 	}
@@ -132,50 +369,162 @@ func (p *TuringPiProvider) Configure(ctx context.Context, req provider.Configure
 			path.Root("host"),
 			"Missing Turing Pi BMC Host",
 			"The provider cannot create the Turing Pi client as there is a missing or empty value for the BMC host. "+
-				"Set the host value in the configuration or use the TURINGPI_HOST environment variable.",
+				"Set the host value in the configuration, select a profile or use the TURINGPI_HOST environment variable.",
 		)
 	}
 
-	// Get username from config or environment, default to "root"
+	// Get username from config, profile or environment, default to "root"
 	username := config.Username.ValueString()
+	if username == "" {
+		username = profile.Username
+	}
 	if username == "" {
 		username = os.Getenv("TURINGPI_USERNAME")
 	}
+	usernameSet := username != ""
 	if username == "" {
 		username = "root"
 	}
 
-	// Get password from config or environment, default to "turing"
+	// Get password from config, profile or environment, default to "turing"
 	password := config.Password.ValueString()
+	if password == "" {
+		password = profile.Password
+	}
 	if password == "" {
 		password = os.Getenv("TURINGPI_PASSWORD")
 	}
+	passwordSet := password != ""
 	if password == "" {
 		password = "turing"
 	}
 
+	// Get the session token from config or environment. A token without
+	// explicit credentials must not fall back to the default credentials,
+	// so an expired token fails instead of silently logging in as root.
+	token := config.Token.ValueString()
+	if token == "" {
+		token = os.Getenv("TURINGPI_TOKEN")
+	}
+	apiUsername, apiPassword := username, password
+	if token != "" && !usernameSet && !passwordSet {
+		apiUsername, apiPassword = "", ""
+	}
+
 	// Get SSH credentials, defaulting to BMC credentials
 	sshUser := config.SSHUser.ValueString()
+	if sshUser == "" {
+		sshUser = profile.SSHUser
+	}
 	if sshUser == "" {
 		sshUser = username
 	}
 
+	// Only fall back to the BMC password when no key-based method is configured,
+	// so key-only BMCs are never offered a password they will reject.
+	sshPrivateKey := config.SSHPrivateKey.ValueString()
+	sshPrivateKeyPath := config.SSHPrivateKeyPath.ValueString()
+	if sshPrivateKey == "" && sshPrivateKeyPath == "" {
+		sshPrivateKeyPath = profile.SSHPrivateKeyPath
+	}
+	sshUseAgent := config.SSHUseAgent.ValueBool()
+	if config.SSHUseAgent.IsNull() && profile.SSHUseAgent != nil {
+		sshUseAgent = *profile.SSHUseAgent
+	}
+
 	sshPassword := config.SSHPassword.ValueString()
 	if sshPassword == "" {
+		sshPassword = profile.SSHPassword
+	}
+	if sshPassword == "" && sshPrivateKey == "" && sshPrivateKeyPath == "" && !sshUseAgent {
 		sshPassword = password
 	}
 
 	sshPort := int(config.SSHPort.ValueInt64())
+	if sshPort == 0 {
+		sshPort = profile.SSHPort
+	}
 	if sshPort == 0 {
 		sshPort = 22
 	}
 
+	sshKnownHostsFile := config.SSHKnownHostsFile.ValueString()
+	if sshKnownHostsFile == "" {
+		sshKnownHostsFile = profile.SSHKnownHostsFile
+	}
+	sshHostKeyFingerprint := config.SSHHostKeyFingerprint.ValueString()
+	if sshHostKeyFingerprint == "" {
+		sshHostKeyFingerprint = profile.SSHHostKeyFingerprint
+	}
+
+	// Without a CA to verify against, keep accepting the self-signed
+	// certificate BMC firmware ships with
+	caCertPEM := config.CACertPEM.ValueString()
+	insecureSkipVerify := caCertPEM == ""
+	if !config.InsecureSkipVerify.IsNull() {
+		insecureSkipVerify = config.InsecureSkipVerify.ValueBool()
+	}
+	if insecureSkipVerify && caCertPEM != "" {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("insecure_skip_verify"),
+			"Conflicting TLS Settings",
+			"insecure_skip_verify = true skips certificate verification, so the ca_cert_pem certificate would never be checked. "+
+				"Remove insecure_skip_verify to verify the BMC against ca_cert_pem, or remove ca_cert_pem.",
+		)
+	}
+
+	var retry client.RetryPolicy
+	if config.Retry != nil {
+		retry.MaxAttempts = int(config.Retry.MaxAttempts.ValueInt64())
+		retry.InitialBackoff = parseDuration(resp, path.Root("retry").AtName("initial_backoff"), config.Retry.InitialBackoff)
+		retry.MaxBackoff = parseDuration(resp, path.Root("retry").AtName("max_backoff"), config.Retry.MaxBackoff)
+	}
+
+	var boardLockTimeout, nodeLockTimeout time.Duration
+	if config.Locks != nil {
+		boardLockTimeout = parseDuration(resp, path.Root("locks").AtName("board_timeout"), config.Locks.BoardTimeout)
+		nodeLockTimeout = parseDuration(resp, path.Root("locks").AtName("node_timeout"), config.Locks.NodeTimeout)
+	}
+
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
 	// Create client wrapper
-	clientWrapper, err := client.NewClient(host, username, password, sshUser, sshPassword, sshPort)
+	clientWrapper, err := client.NewClient(ctx, client.Config{
+		Host:              host,
+		Username:          apiUsername,
+		Password:          apiPassword,
+		Token:             token,
+		SSHUser:           sshUser,
+		SSHPassword:       sshPassword,
+		SSHPort:           sshPort,
+		SSHPrivateKey:     sshPrivateKey,
+		SSHPrivateKeyPath: sshPrivateKeyPath,
+		SSHUseAgent:       sshUseAgent,
+
+		SSHKnownHostsFile:     sshKnownHostsFile,
+		SSHHostKeyFingerprint: sshHostKeyFingerprint,
+
+		Scheme: config.Scheme.ValueString(),
+		TLS: client.TLSConfig{
+			CACertPEM:          caCertPEM,
+			InsecureSkipVerify: insecureSkipVerify,
+			ClientCertPEM:      config.ClientCertPEM.ValueString(),
+			ClientKeyPEM:       config.ClientKeyPEM.ValueString(),
+		},
+
+		Retry: retry,
+
+		BoardLockTimeout: boardLockTimeout,
+		NodeLockTimeout:  nodeLockTimeout,
+
+		DefaultCache: profile.Cache,
+
+		ReadOnly: config.ReadOnly.ValueBool(),
+
+		AuditLogPath: config.AuditLogPath.ValueString(),
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to Create Turing Pi Client",
@@ -186,9 +535,40 @@ func (p *TuringPiProvider) Configure(ctx context.Context, req provider.Configure
 		return
 	}
 
+	// Registered before preflight, which may already open the SSH connection
+	configured.mu.Lock()
+	configured.clients = append(configured.clients, clientWrapper)
+	configured.mu.Unlock()
+
+	if config.Preflight.ValueBool() {
+		runPreflight(ctx, clientWrapper, config, token != "" && apiPassword == "", resp)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
 	// Make the client available to resources and data sources
 	resp.DataSourceData = clientWrapper
 	resp.ResourceData = clientWrapper
+	resp.EphemeralResourceData = clientWrapper
+}
+
+// parseDuration parses an optional Go duration attribute, recording an
+// attribute error on failure. Null values yield zero.
+func parseDuration(resp *provider.ConfigureResponse, attrPath path.Path, value types.String) time.Duration {
+	if value.IsNull() || value.ValueString() == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(value.ValueString())
+	if err != nil || d <= 0 {
+		resp.Diagnostics.AddAttributeError(
+			attrPath,
+			"Invalid Duration",
+			fmt.Sprintf("Expected a positive Go duration such as \"500ms\" or \"2s\", got %q.", value.ValueString()),
+		)
+		return 0
+	}
+	return d
 }
 
 func (p *TuringPiProvider) Resources(ctx context.Context) []func() resource.Resource {
@@ -206,3 +586,17 @@ func (p *TuringPiProvider) DataSources(ctx context.Context) []func() datasource.
 		usb_status.NewUsbStatusDataSource,
 	}
 }
+
+func (p *TuringPiProvider) EphemeralResources(ctx context.Context) []func() ephemeral.EphemeralResource {
+	return []func() ephemeral.EphemeralResource{
+		bmc_session.NewBMCSessionEphemeralResource,
+	}
+}
+
+func (p *TuringPiProvider) Functions(ctx context.Context) []func() function.Function {
+	return []func() function.Function{
+		functions.NewDetectCompressionFunction,
+		functions.NewFileSHA256Function,
+		functions.NewNodeIDFunction,
+	}
+}
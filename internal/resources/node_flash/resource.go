@@ -7,19 +7,21 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/davidroman0O/terraform-provider-turingpi/internal/client"
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/diagnostics"
 	tpi "github.com/davidroman0O/tpi/client"
 	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
-	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
@@ -35,6 +37,7 @@ const (
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &NodeFlashResource{}
 var _ resource.ResourceWithImportState = &NodeFlashResource{}
+var _ resource.ResourceWithModifyPlan = &NodeFlashResource{}
 
 func NewNodeFlashResource() resource.Resource {
 	return &NodeFlashResource{}
@@ -42,21 +45,27 @@ func NewNodeFlashResource() resource.Resource {
 
 // NodeFlashResource defines the resource implementation.
 type NodeFlashResource struct {
-	client *client.Client
+	client client.BMC
 }
 
 // NodeFlashResourceModel describes the resource data model.
 type NodeFlashResourceModel struct {
-	ID          types.String   `tfsdk:"id"`
-	Node        types.Int64    `tfsdk:"node"`
-	ImageURL    types.String   `tfsdk:"image_url"`
-	ImagePath   types.String   `tfsdk:"image_path"`
-	SHA256      types.String   `tfsdk:"sha256"`
-	Cache       types.String   `tfsdk:"cache"`
-	SkipCRC     types.Bool     `tfsdk:"skip_crc"`
-	FlashStatus types.String   `tfsdk:"flash_status"`
-	LastFlashed types.String   `tfsdk:"last_flashed"`
-	Timeouts    timeouts.Value `tfsdk:"timeouts"`
+	ID           types.String   `tfsdk:"id"`
+	Node         types.Int64    `tfsdk:"node"`
+	ImageURL     types.String   `tfsdk:"image_url"`
+	ImagePath    types.String   `tfsdk:"image_path"`
+	SHA256       types.String   `tfsdk:"sha256"`
+	ChecksumURL  types.String   `tfsdk:"checksum_url"`
+	SignatureURL types.String   `tfsdk:"signature_url"`
+	TrustedKeys  types.List     `tfsdk:"trusted_keys"`
+	Cache        types.String   `tfsdk:"cache"`
+	SkipCRC      types.Bool     `tfsdk:"skip_crc"`
+	Connections  types.Int64    `tfsdk:"download_connections"`
+	Compression  types.String   `tfsdk:"compression"`
+	Member       types.String   `tfsdk:"archive_member"`
+	FlashStatus  types.String   `tfsdk:"flash_status"`
+	LastFlashed  types.String   `tfsdk:"last_flashed"`
+	Timeouts     timeouts.Value `tfsdk:"timeouts"`
 }
 
 func (r *NodeFlashResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -68,7 +77,8 @@ func (r *NodeFlashResource) Schema(ctx context.Context, req resource.SchemaReque
 		Description: "Flashes an OS image to a Turing Pi 2 node.",
 		MarkdownDescription: `Flashes an OS image to a Turing Pi 2 node.
 
-This resource supports downloading images from URLs with automatic decompression for .xz, .gz, and .zip formats.
+This resource supports downloading images from URLs with automatic decompression of xz, gzip, zip, zstd, bzip2 and lz4 images, recognized by their first bytes.
+Zip and tar archives are unpacked to the image they hold.
 Images can be cached locally or on the BMC to speed up subsequent flashes.
 
 ## Example Usage
@@ -102,8 +112,8 @@ resource "turingpi_node_flash" "ubuntu" {
 				},
 			},
 			"image_url": schema.StringAttribute{
-				Description:         "URL to download the OS image from. Supports .xz, .gz, and .zip compression.",
-				MarkdownDescription: "URL to download the OS image from. Supports `.xz`, `.gz`, and `.zip` compression.",
+				Description:         "URL to download the OS image from. Supports xz, gzip, zip, zstd, bzip2 and lz4 compression, and zip, tar, tar.gz and tar.xz archives.",
+				MarkdownDescription: "URL to download the OS image from. Supports xz, gzip, zip, zstd, bzip2 and lz4 compression, and zip, tar, tar.gz and tar.xz archives.",
 				Optional:            true,
 				Validators: []validator.String{
 					stringvalidator.ExactlyOneOf(
@@ -113,8 +123,9 @@ resource "turingpi_node_flash" "ubuntu" {
 				},
 			},
 			"image_path": schema.StringAttribute{
-				Description: "Local file path to the OS image.",
-				Optional:    true,
+				Description:         "Local file path to the OS image. Compressed images are decompressed before flashing; see compression.",
+				MarkdownDescription: "Local file path to the OS image. Compressed images are decompressed before flashing; see `compression`.",
+				Optional:            true,
 			},
 			"sha256": schema.StringAttribute{
 				Description:         "SHA256 checksum for image verification and cache key. If not provided, it will be calculated automatically.",
@@ -122,16 +133,68 @@ resource "turingpi_node_flash" "ubuntu" {
 				Optional:            true,
 				Computed:            true,
 			},
+			"checksum_url": schema.StringAttribute{
+				Description:         "URL of a SHA256SUMS-style checksum file listing the image by its file name. The downloaded file, or image_path, must match its entry before it is flashed.",
+				MarkdownDescription: "URL of a `SHA256SUMS`-style checksum file listing the image by its file name. The downloaded file, or `image_path`, must match its entry before it is flashed.",
+				Optional:            true,
+			},
+			"signature_url": schema.StringAttribute{
+				Description:         "URL of a detached OpenPGP or minisign signature of the checksum file, which must be made by one of trusted_keys.",
+				MarkdownDescription: "URL of a detached OpenPGP or minisign signature of the checksum file, which must be made by one of `trusted_keys`.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.AlsoRequires(
+						path.MatchRoot("checksum_url"),
+						path.MatchRoot("trusted_keys"),
+					),
+				},
+			},
+			"trusted_keys": schema.ListAttribute{
+				Description:         "Public keys trusted to sign the checksum file: ASCII-armored OpenPGP keys or minisign public keys.",
+				MarkdownDescription: "Public keys trusted to sign the checksum file: ASCII-armored OpenPGP keys or minisign public keys.",
+				ElementType:         types.StringType,
+				Optional:            true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+					listvalidator.AlsoRequires(path.MatchRoot("signature_url")),
+				},
+			},
 			"cache": schema.StringAttribute{
-				Description:         "Cache strategy: 'local' (local filesystem), 'bmc' (BMC filesystem via SFTP), or 'none' (no caching). Default: 'none'.",
-				MarkdownDescription: "Cache strategy: `local` (local filesystem), `bmc` (BMC filesystem via SFTP), or `none` (no caching). Default: `none`.",
+				Description:         "Cache strategy: 'local' (local filesystem), 'bmc' (BMC filesystem via SFTP), or 'none' (no caching). Default: the provider profile's cache, otherwise 'none'.",
+				MarkdownDescription: "Cache strategy: `local` (local filesystem), `bmc` (BMC filesystem via SFTP), or `none` (no caching). Default: the provider profile's `cache`, otherwise `none`.",
 				Optional:            true,
 				Computed:            true,
-				Default:             stringdefault.StaticString("none"),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
 				Validators: []validator.String{
 					stringvalidator.OneOf("local", "bmc", "none"),
 				},
 			},
+			"compression": schema.StringAttribute{
+				Description:         "Compression of the image: 'xz', 'gz', 'zip', 'zst', 'bz2', 'lz4', 'tar', or 'none'. Default: detected from the first bytes of the image, then its file name and Content-Type.",
+				MarkdownDescription: "Compression of the image: `xz`, `gz`, `zip`, `zst`, `bz2`, `lz4`, `tar`, or `none`. Default: detected from the first bytes of the image, then its file name and `Content-Type`.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(append([]string{client.CompressionNone}, client.Compressions...)...),
+				},
+			},
+			"archive_member": schema.StringAttribute{
+				Description:         "Name or glob of the file to flash from a zip or tar archive, e.g. 'images/*.img'. A glob without a slash also matches files in subdirectories by base name. Default: the largest .img or .raw file, or the only file of the archive.",
+				MarkdownDescription: "Name or glob of the file to flash from a zip or tar archive, e.g. `images/*.img`. A glob without a slash also matches files in subdirectories by base name. Default: the largest `.img` or `.raw` file, or the only file of the archive.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
+			"download_connections": schema.Int64Attribute{
+				Description:         "Number of concurrent connections used to download image_url (1-16). Servers that accept byte ranges send large images in parallel chunks. Default: 1.",
+				MarkdownDescription: "Number of concurrent connections used to download `image_url` (`1`-`16`). Servers that accept byte ranges send large images in parallel chunks. Default: `1`.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.Between(1, 16),
+				},
+			},
 			"skip_crc": schema.BoolAttribute{
 				Description:         "Skip CRC verification during flash. Default: false.",
 				MarkdownDescription: "Skip CRC verification during flash. Default: `false`.",
@@ -162,11 +225,11 @@ func (r *NodeFlashResource) Configure(ctx context.Context, req resource.Configur
 		return
 	}
 
-	client, ok := req.ProviderData.(*client.Client)
+	client, ok := req.ProviderData.(client.BMC)
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Resource Configure Type",
-			fmt.Sprintf("Expected *client.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected client.BMC, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 		return
 	}
@@ -174,6 +237,28 @@ func (r *NodeFlashResource) Configure(ctx context.Context, req resource.Configur
 	r.client = client
 }
 
+// ModifyPlan rejects changes when the provider is read-only, and fills in the
+// cache strategy from the provider default when the configuration leaves it
+// unset. Existing resources keep their stored value.
+func (r *NodeFlashResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	resp.Diagnostics.Append(diagnostics.ReadOnlyPlan(r.client, req, "turingpi_node_flash")...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if req.Plan.Raw.IsNull() || r.client == nil {
+		return
+	}
+
+	var cache types.String
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("cache"), &cache)...)
+	if resp.Diagnostics.HasError() || !cache.IsUnknown() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("cache"), types.StringValue(r.client.DefaultCacheLocation()))...)
+}
+
 func (r *NodeFlashResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
 	var plan NodeFlashResourceModel
 
@@ -200,10 +285,11 @@ func (r *NodeFlashResource) Create(ctx context.Context, req resource.CreateReque
 	// Execute flash
 	result, err := r.executeFlash(ctx, &plan)
 	if err != nil {
-		resp.Diagnostics.AddError(
+		resp.Diagnostics.Append(diagnostics.ClientError(
 			"Flash Operation Failed",
-			fmt.Sprintf("Failed to flash node %d: %s", plan.Node.ValueInt64(), err.Error()),
-		)
+			fmt.Sprintf("Failed to flash node %d", plan.Node.ValueInt64()),
+			err,
+		))
 		plan.FlashStatus = types.StringValue("failed")
 		resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 		return
@@ -262,10 +348,11 @@ func (r *NodeFlashResource) Update(ctx context.Context, req resource.UpdateReque
 	// Re-flash on changes to image_url, image_path, or sha256
 	result, err := r.executeFlash(ctx, &plan)
 	if err != nil {
-		resp.Diagnostics.AddError(
+		resp.Diagnostics.Append(diagnostics.ClientError(
 			"Flash Update Failed",
-			fmt.Sprintf("Failed to re-flash node %d: %s", plan.Node.ValueInt64(), err.Error()),
-		)
+			fmt.Sprintf("Failed to re-flash node %d", plan.Node.ValueInt64()),
+			err,
+		))
 		plan.FlashStatus = types.StringValue("failed")
 		resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 		return
@@ -296,11 +383,18 @@ type FlashResult struct {
 // executeFlash handles the actual flash operation with caching.
 func (r *NodeFlashResource) executeFlash(ctx context.Context, plan *NodeFlashResourceModel) (*FlashResult, error) {
 	node := int(plan.Node.ValueInt64())
+
+	// The cache is still unknown when the provider configuration was not
+	// known at plan time
+	if plan.Cache.IsUnknown() || plan.Cache.IsNull() {
+		plan.Cache = types.StringValue(r.client.DefaultCacheLocation())
+	}
 	cacheLocation := plan.Cache.ValueString()
 
 	var imagePath string
 	var sha256 string
 	var tempFile string // Track temp file for cleanup
+	var onBMC bool      // imagePath is on the BMC filesystem
 
 	// Initialize cache
 	cache, err := client.NewImageCache(r.client)
@@ -308,6 +402,13 @@ func (r *NodeFlashResource) executeFlash(ctx context.Context, plan *NodeFlashRes
 		return nil, fmt.Errorf("failed to initialize cache: %w", err)
 	}
 
+	// Look up the published checksum before anything is downloaded, so a
+	// bad signature or a missing entry fails the flash early
+	publishedSHA256, err := r.publishedChecksum(ctx, plan)
+	if err != nil {
+		return nil, err
+	}
+
 	// Handle image source
 	if !plan.ImageURL.IsNull() && plan.ImageURL.ValueString() != "" {
 		// Download from URL
@@ -319,9 +420,13 @@ func (r *NodeFlashResource) executeFlash(ctx context.Context, plan *NodeFlashRes
 		var expectedSHA256 string
 		if !plan.SHA256.IsNull() && plan.SHA256.ValueString() != "" {
 			expectedSHA256 = plan.SHA256.ValueString()
+		}
 
-			// Check cache first
-			cachedPath, err := cache.GetCachedImagePath(expectedSHA256, cacheLocation)
+		// Check cache first. Cache entries are keyed by the image's SHA256,
+		// while checksum_url lists the downloaded file, so a cached image
+		// cannot be verified against it and is only used without one
+		if expectedSHA256 != "" && publishedSHA256 == "" {
+			cachedPath, err := cache.GetCachedImagePath(ctx, expectedSHA256, cacheLocation)
 			if err != nil {
 				tflog.Warn(ctx, "Failed to check cache", map[string]interface{}{
 					"error": err.Error(),
@@ -332,6 +437,7 @@ func (r *NodeFlashResource) executeFlash(ctx context.Context, plan *NodeFlashRes
 				})
 				imagePath = cachedPath
 				sha256 = expectedSHA256
+				onBMC = cacheLocation == client.CacheLocationBMC
 			}
 		}
 
@@ -339,22 +445,38 @@ func (r *NodeFlashResource) executeFlash(ctx context.Context, plan *NodeFlashRes
 		if imagePath == "" {
 			result, err := client.DownloadImage(ctx, plan.ImageURL.ValueString(), &client.DownloadOptions{
 				ExpectedSHA256: expectedSHA256,
+				ResumeDir:      cache.PartialDir(),
+				Connections:    int(plan.Connections.ValueInt64()),
+				Compression:    plan.Compression.ValueString(),
+				ArchiveMember:  plan.Member.ValueString(),
+
+				ExpectedCompressedSHA256: publishedSHA256,
 			})
 			if err != nil {
 				return nil, fmt.Errorf("failed to download image: %w", err)
 			}
 			imagePath = result.Path
 			sha256 = result.SHA256
+			tflog.Info(ctx, "Image downloaded", map[string]interface{}{
+				"path":              result.Path,
+				"sha256":            result.SHA256,
+				"compressed_sha256": result.CompressedSHA256,
+			})
 			tempFile = result.Path // Mark for cleanup later
 
 			// Cache the downloaded image if caching is enabled
 			if cacheLocation != client.CacheLocationNone {
-				cachedPath, err := cache.CacheImage(imagePath, sha256, cacheLocation)
+				cachedPath, err := cache.CacheImage(ctx, imagePath, sha256, cacheLocation)
+				if client.IsHostKeyError(err) || ctx.Err() != nil {
+					return nil, err
+				}
 				if err != nil {
 					tflog.Warn(ctx, "Failed to cache image", map[string]interface{}{
 						"error": err.Error(),
 					})
 				} else {
+					imagePath = cachedPath
+					onBMC = cacheLocation == client.CacheLocationBMC
 					tflog.Info(ctx, "Image cached", map[string]interface{}{
 						"path": cachedPath,
 					})
@@ -365,26 +487,64 @@ func (r *NodeFlashResource) executeFlash(ctx context.Context, plan *NodeFlashRes
 		// Use local file
 		imagePath = plan.ImagePath.ValueString()
 
-		// Calculate SHA256 if not provided
-		if !plan.SHA256.IsNull() && plan.SHA256.ValueString() != "" {
+		compression := plan.Compression.ValueString()
+		if compression == "" {
+			compression, err = client.DetectFileCompression(imagePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read image: %w", err)
+			}
+		}
+
+		if compression != "" && compression != client.CompressionNone {
+			// Decompress to a temp file, verifying sha256 against the image
+			tflog.Info(ctx, "Decompressing local image", map[string]interface{}{
+				"path":        imagePath,
+				"compression": compression,
+			})
+			result, err := client.DecompressImage(ctx, imagePath, &client.DownloadOptions{
+				ExpectedSHA256: plan.SHA256.ValueString(),
+				Compression:    compression,
+				ArchiveMember:  plan.Member.ValueString(),
+
+				ExpectedCompressedSHA256: publishedSHA256,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to decompress image: %w", err)
+			}
+			imagePath = result.Path
+			sha256 = result.SHA256
+			tempFile = result.Path
+		} else if publishedSHA256 == "" && !plan.SHA256.IsNull() && plan.SHA256.ValueString() != "" {
+			// Calculate SHA256 if not provided
 			sha256 = plan.SHA256.ValueString()
 		} else {
-			calculatedSHA256, err := client.CalculateFileSHA256(imagePath)
+			calculatedSHA256, err := client.CalculateFileSHA256Context(ctx, imagePath)
 			if err != nil {
 				return nil, fmt.Errorf("failed to calculate SHA256: %w", err)
 			}
+			if publishedSHA256 != "" && calculatedSHA256 != publishedSHA256 {
+				return nil, fmt.Errorf("%w: checksum file lists SHA256 %s for %s, got %s", client.ErrChecksumMismatch, publishedSHA256, imagePath, calculatedSHA256)
+			}
+			// A configured sha256 must hold too, before the node is flashed
+			if configured := plan.SHA256.ValueString(); configured != "" && calculatedSHA256 != configured {
+				return nil, fmt.Errorf("%w: expected SHA256 %s for %s, got %s", client.ErrChecksumMismatch, configured, imagePath, calculatedSHA256)
+			}
 			sha256 = calculatedSHA256
 		}
 
 		// Cache the local file if caching is enabled
 		if cacheLocation != client.CacheLocationNone {
-			cachedPath, err := cache.CacheImage(imagePath, sha256, cacheLocation)
+			cachedPath, err := cache.CacheImage(ctx, imagePath, sha256, cacheLocation)
+			if client.IsHostKeyError(err) || ctx.Err() != nil {
+				return nil, err
+			}
 			if err != nil {
 				tflog.Warn(ctx, "Failed to cache image", map[string]interface{}{
 					"error": err.Error(),
 				})
 			} else {
 				imagePath = cachedPath
+				onBMC = cacheLocation == client.CacheLocationBMC
 				tflog.Info(ctx, "Image cached", map[string]interface{}{
 					"path": cachedPath,
 				})
@@ -392,10 +552,12 @@ func (r *NodeFlashResource) executeFlash(ctx context.Context, plan *NodeFlashRes
 		}
 	}
 
-	// Cleanup temp file if we created one and it's not the cache path
+	// Cleanup the temp file and its directory once the flash is done; cached
+	// copies live elsewhere
 	defer func() {
-		if tempFile != "" && tempFile != imagePath {
+		if tempFile != "" {
 			os.Remove(tempFile)
+			os.Remove(filepath.Dir(tempFile))
 		}
 	}()
 
@@ -406,16 +568,17 @@ func (r *NodeFlashResource) executeFlash(ctx context.Context, plan *NodeFlashRes
 		"sha256": sha256[:16] + "...",
 	})
 
-	// Use FlashNodeLocal if the image is on BMC
-	if cacheLocation == client.CacheLocationBMC {
-		err = r.client.FlashNodeLocal(node, imagePath)
+	// Use FlashNodeLocal if the image is on BMC. When caching to the BMC
+	// failed, the local image is uploaded instead
+	if onBMC {
+		err = r.client.FlashNodeLocalContext(ctx, node, imagePath)
 	} else {
 		opts := &tpi.FlashOptions{
 			ImagePath: imagePath,
 			SHA256:    sha256,
 			SkipCRC:   plan.SkipCRC.ValueBool(),
 		}
-		err = r.client.FlashNode(node, opts)
+		err = r.client.FlashNodeContext(ctx, node, opts)
 	}
 
 	if err != nil {
@@ -426,3 +589,37 @@ func (r *NodeFlashResource) executeFlash(ctx context.Context, plan *NodeFlashRes
 		SHA256: sha256,
 	}, nil
 }
+
+// publishedChecksum returns the SHA256 that checksum_url lists for the image
+// file, after verifying the checksum file's signature when signature_url is
+// set, or "" without a checksum_url.
+func (r *NodeFlashResource) publishedChecksum(ctx context.Context, plan *NodeFlashResourceModel) (string, error) {
+	if plan.ChecksumURL.IsNull() || plan.ChecksumURL.ValueString() == "" {
+		return "", nil
+	}
+
+	var trustedKeys []string
+	if diags := plan.TrustedKeys.ElementsAs(ctx, &trustedKeys, false); diags.HasError() {
+		return "", fmt.Errorf("invalid trusted_keys: %v", diags)
+	}
+
+	name := filepath.Base(plan.ImagePath.ValueString())
+	if !plan.ImageURL.IsNull() && plan.ImageURL.ValueString() != "" {
+		name = client.ImageFileName(plan.ImageURL.ValueString())
+	}
+
+	sum, err := client.FetchChecksum(ctx, name, &client.ChecksumOptions{
+		URL:          plan.ChecksumURL.ValueString(),
+		SignatureURL: plan.SignatureURL.ValueString(),
+		TrustedKeys:  trustedKeys,
+	})
+	if err != nil {
+		return "", err
+	}
+	tflog.Info(ctx, "Published checksum found", map[string]interface{}{
+		"file":     name,
+		"sha256":   sum,
+		"verified": plan.SignatureURL.ValueString() != "",
+	})
+	return sum, nil
+}
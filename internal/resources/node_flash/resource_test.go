@@ -0,0 +1,434 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package node_flash
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/client"
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/client/fake"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// imageSHA256 is the SHA256 of the test image contents "turingpi".
+const imageSHA256 = "d5a301457e031f43e52722c9440f89a94d59eee373a569919e30e0dbaee419b5"
+
+// newTestResource returns a resource configured with bmc and an empty state.
+// HOME is redirected so the local cache stays inside the test.
+func newTestResource(t *testing.T, bmc *fake.BMC) (*NodeFlashResource, tfsdk.State) {
+	t.Setenv("HOME", t.TempDir())
+	return fake.ConfigureResource(t, &NodeFlashResource{}, bmc)
+}
+
+// writeImage creates a local image file and returns its path.
+func writeImage(t *testing.T) string {
+	t.Helper()
+	imagePath := filepath.Join(t.TempDir(), "image.img")
+	if err := os.WriteFile(imagePath, []byte("turingpi"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return imagePath
+}
+
+func model(node int64, imagePath, cache string) *NodeFlashResourceModel {
+	cacheValue := types.StringValue(cache)
+	if cache == "" {
+		cacheValue = types.StringUnknown()
+	}
+	return &NodeFlashResourceModel{
+		ID:          types.StringUnknown(),
+		Node:        types.Int64Value(node),
+		ImageURL:    types.StringNull(),
+		ImagePath:   types.StringValue(imagePath),
+		SHA256:      types.StringUnknown(),
+		TrustedKeys: types.ListNull(types.StringType),
+		Cache:       cacheValue,
+		SkipCRC:     types.BoolValue(false),
+		FlashStatus: types.StringUnknown(),
+		LastFlashed: types.StringUnknown(),
+		Timeouts: timeouts.Value{Object: types.ObjectNull(map[string]attr.Type{
+			"create": types.StringType,
+			"update": types.StringType,
+		})},
+	}
+}
+
+func create(t *testing.T, r *NodeFlashResource, empty tfsdk.State, m *NodeFlashResourceModel) resource.CreateResponse {
+	t.Helper()
+	ctx := context.Background()
+
+	plan := tfsdk.Plan{Schema: empty.Schema, Raw: empty.Raw}
+	if diags := plan.Set(ctx, m); diags.HasError() {
+		t.Fatal(diags)
+	}
+	resp := resource.CreateResponse{State: empty}
+	r.Create(ctx, resource.CreateRequest{Plan: plan}, &resp)
+	return resp
+}
+
+func TestNodeFlashCreateFromLocalImage(t *testing.T) {
+	ctx := context.Background()
+	bmc := fake.New()
+	r, empty := newTestResource(t, bmc)
+	imagePath := writeImage(t)
+
+	resp := create(t, r, empty, model(2, imagePath, client.CacheLocationNone))
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Create: %v", resp.Diagnostics)
+	}
+
+	var state NodeFlashResourceModel
+	resp.State.Get(ctx, &state)
+	if state.SHA256.ValueString() != imageSHA256 {
+		t.Errorf("sha256 = %s, want %s", state.SHA256, imageSHA256)
+	}
+	if state.ID.ValueString() != "node-2-flash-"+imageSHA256[:8] || state.FlashStatus.ValueString() != "success" {
+		t.Errorf("state = %+v", state)
+	}
+
+	record, ok := bmc.Flashed(2)
+	if !ok || record.ImagePath != imagePath || record.SHA256 != imageSHA256 || record.OnBMC {
+		t.Errorf("flash record = %+v, %v", record, ok)
+	}
+}
+
+func TestNodeFlashCreateFromCompressedLocalImage(t *testing.T) {
+	ctx := context.Background()
+	bmc := fake.New()
+	r, empty := newTestResource(t, bmc)
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte("turingpi"))
+	gz.Close()
+	imagePath := filepath.Join(t.TempDir(), "image.img.gz")
+	if err := os.WriteFile(imagePath, compressed.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := create(t, r, empty, model(3, imagePath, client.CacheLocationNone))
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Create: %v", resp.Diagnostics)
+	}
+
+	var state NodeFlashResourceModel
+	resp.State.Get(ctx, &state)
+	if state.SHA256.ValueString() != imageSHA256 {
+		t.Errorf("sha256 = %s, want the SHA256 of the decompressed image %s", state.SHA256, imageSHA256)
+	}
+
+	record, ok := bmc.Flashed(3)
+	if !ok || record.ImagePath == imagePath || record.SHA256 != imageSHA256 {
+		t.Errorf("flash record = %+v, %v; want the decompressed image", record, ok)
+	}
+	if _, err := os.Stat(record.ImagePath); !os.IsNotExist(err) {
+		t.Errorf("decompressed image %s not cleaned up", record.ImagePath)
+	}
+}
+
+func TestNodeFlashCreateCompressionOverride(t *testing.T) {
+	ctx := context.Background()
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte("turingpi"))
+	gz.Close()
+	tests := []struct {
+		name        string
+		file        string
+		compression types.String
+		want        string // SHA256 of the flashed image
+	}{
+		{"sniffed without extension", "image.bin", types.StringNull(), imageSHA256},
+		{"explicit", "image.bin", types.StringValue("gz"), imageSHA256},
+		{"none", "image.img.gz", types.StringValue(client.CompressionNone), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := fake.New()
+			r, empty := newTestResource(t, bmc)
+			imagePath := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(imagePath, compressed.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			m := model(1, imagePath, client.CacheLocationNone)
+			m.Compression = tt.compression
+			resp := create(t, r, empty, m)
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("Create: %v", resp.Diagnostics)
+			}
+
+			want := tt.want
+			if want == "" {
+				want, _ = client.CalculateFileSHA256(imagePath)
+			}
+			var state NodeFlashResourceModel
+			resp.State.Get(ctx, &state)
+			if state.SHA256.ValueString() != want {
+				t.Errorf("sha256 = %s, want %s", state.SHA256, want)
+			}
+		})
+	}
+}
+
+func TestNodeFlashCreateFromArchiveMember(t *testing.T) {
+	ctx := context.Background()
+	bmc := fake.New()
+	r, empty := newTestResource(t, bmc)
+
+	// A bundle where the image is neither first nor the largest file
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	tw := tar.NewWriter(gz)
+	for _, f := range []struct{ name, data string }{
+		{"README.md", "Flash turingpi.img to your node, or the recovery image."},
+		{"turingpi.img", "turingpi"},
+		{"recovery.img", "recovery image, larger than the other"},
+	} {
+		tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.data))})
+		tw.Write([]byte(f.data))
+	}
+	tw.Close()
+	gz.Close()
+	imagePath := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	if err := os.WriteFile(imagePath, compressed.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := model(2, imagePath, client.CacheLocationNone)
+	m.Member = types.StringValue("turingpi.*")
+	resp := create(t, r, empty, m)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Create: %v", resp.Diagnostics)
+	}
+
+	var state NodeFlashResourceModel
+	resp.State.Get(ctx, &state)
+	if state.SHA256.ValueString() != imageSHA256 {
+		t.Errorf("sha256 = %s, want the SHA256 of the archive member %s", state.SHA256, imageSHA256)
+	}
+}
+
+func TestNodeFlashCreateWithBMCCache(t *testing.T) {
+	bmc := fake.New()
+	r, empty := newTestResource(t, bmc)
+
+	resp := create(t, r, empty, model(1, writeImage(t), client.CacheLocationBMC))
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Create: %v", resp.Diagnostics)
+	}
+
+	record, ok := bmc.Flashed(1)
+	if !ok || !record.OnBMC {
+		t.Fatalf("flash record = %+v, %v; want a flash from the BMC filesystem", record, ok)
+	}
+	if data, ok := bmc.File(record.ImagePath); !ok || string(data) != "turingpi" {
+		t.Errorf("cached image %s missing or wrong on the BMC", record.ImagePath)
+	}
+}
+
+func TestNodeFlashCreateFromURLWithBMCCache(t *testing.T) {
+	bmc := fake.New()
+	r, empty := newTestResource(t, bmc)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "turingpi")
+	}))
+	defer server.Close()
+
+	m := model(1, "", client.CacheLocationBMC)
+	m.ImagePath = types.StringNull()
+	m.ImageURL = types.StringValue(server.URL + "/image.img")
+	resp := create(t, r, empty, m)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Create: %v", resp.Diagnostics)
+	}
+
+	record, ok := bmc.Flashed(1)
+	if !ok || !record.OnBMC {
+		t.Fatalf("flash record = %+v, %v; want a flash from the BMC filesystem", record, ok)
+	}
+	if data, ok := bmc.File(record.ImagePath); !ok || string(data) != "turingpi" {
+		t.Errorf("flashed %s, which is not the cached image on the BMC", record.ImagePath)
+	}
+}
+
+func TestNodeFlashCreateBMCCacheFailureUploads(t *testing.T) {
+	bmc := fake.New()
+	bmc.FailNext("UploadFile", errors.New("no space left on device"))
+	r, empty := newTestResource(t, bmc)
+	imagePath := writeImage(t)
+
+	resp := create(t, r, empty, model(1, imagePath, client.CacheLocationBMC))
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Create: %v", resp.Diagnostics)
+	}
+
+	record, ok := bmc.Flashed(1)
+	if !ok || record.OnBMC || record.ImagePath != imagePath {
+		t.Fatalf("flash record = %+v, %v; want an upload of %s", record, ok, imagePath)
+	}
+}
+
+func TestNodeFlashCreateFailure(t *testing.T) {
+	ctx := context.Background()
+	bmc := fake.New()
+	bmc.FailNext("FlashNode", errors.New("flash failed: CRC mismatch"))
+	r, empty := newTestResource(t, bmc)
+
+	resp := create(t, r, empty, model(3, writeImage(t), client.CacheLocationNone))
+	if !resp.Diagnostics.HasError() {
+		t.Fatal("expected an error diagnostic")
+	}
+	if !strings.Contains(resp.Diagnostics.Errors()[0].Detail(), "CRC mismatch") {
+		t.Errorf("diagnostic does not mention the cause: %v", resp.Diagnostics)
+	}
+
+	var state NodeFlashResourceModel
+	resp.State.Get(ctx, &state)
+	if state.FlashStatus.ValueString() != "failed" {
+		t.Errorf("flash_status = %s, want failed", state.FlashStatus)
+	}
+}
+
+func TestNodeFlashCreateChecksumMismatch(t *testing.T) {
+	bmc := fake.New()
+	bmc.FailNext("FlashNode", fmt.Errorf("%w: provided SHA256 00, calculated %s", client.ErrChecksumMismatch, imageSHA256))
+	r, empty := newTestResource(t, bmc)
+
+	resp := create(t, r, empty, model(3, writeImage(t), client.CacheLocationNone))
+	if !resp.Diagnostics.HasError() {
+		t.Fatal("expected an error diagnostic")
+	}
+	if got := resp.Diagnostics.Errors()[0].Summary(); got != "Image Checksum Mismatch" {
+		t.Errorf("summary = %q, want Image Checksum Mismatch", got)
+	}
+}
+
+func TestNodeFlashCreateChecksumURL(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte("turingpi"))
+	gz.Close()
+	compressedSum := sha256.Sum256(compressed.Bytes())
+	sums := hex.EncodeToString(compressedSum[:]) + "  image.img.gz\n" + imageSHA256 + "  image.img\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, sums)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		file    string
+		data    []byte
+		sha256  string // Configured sha256, if any
+		wantErr bool
+	}{
+		{"compressed", "image.img.gz", compressed.Bytes(), "", false},
+		{"uncompressed", "image.img", []byte("turingpi"), "", false},
+		{"uncompressed with sha256", "image.img", []byte("turingpi"), imageSHA256, false},
+		{"sha256 disagrees", "image.img", []byte("turingpi"), strings.Repeat("0", 64), true},
+		{"tampered", "image.img", []byte("tampered"), "", true},
+		{"not listed", "other.img", []byte("turingpi"), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := fake.New()
+			r, empty := newTestResource(t, bmc)
+			imagePath := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(imagePath, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			m := model(1, imagePath, client.CacheLocationNone)
+			m.ChecksumURL = types.StringValue(server.URL + "/SHA256SUMS")
+			if tt.sha256 != "" {
+				m.SHA256 = types.StringValue(tt.sha256)
+			}
+			resp := create(t, r, empty, m)
+			if resp.Diagnostics.HasError() != tt.wantErr {
+				t.Fatalf("Create: %v, want error %v", resp.Diagnostics, tt.wantErr)
+			}
+			if _, flashed := bmc.Flashed(1); flashed == tt.wantErr {
+				t.Errorf("flashed = %v, want %v", flashed, !tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNodeFlashCreateChecksumURLBypassesCache(t *testing.T) {
+	bmc := fake.New()
+	r, empty := newTestResource(t, bmc)
+
+	// The image is cached under its sha256, but the mirror now serves
+	// something else than the checksum file lists
+	cache, err := client.NewImageCache(bmc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.CacheImage(context.Background(), writeImage(t), imageSHA256, client.CacheLocationLocal); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/SHA256SUMS" {
+			fmt.Fprint(w, imageSHA256+"  image.img\n")
+			return
+		}
+		fmt.Fprint(w, "tampered")
+	}))
+	defer server.Close()
+
+	m := model(1, "", client.CacheLocationLocal)
+	m.ImagePath = types.StringNull()
+	m.ImageURL = types.StringValue(server.URL + "/image.img")
+	m.SHA256 = types.StringValue(imageSHA256)
+	m.ChecksumURL = types.StringValue(server.URL + "/SHA256SUMS")
+	resp := create(t, r, empty, m)
+	if !resp.Diagnostics.HasError() {
+		t.Fatal("expected the tampered download to be rejected instead of the cached image being flashed")
+	}
+	if _, flashed := bmc.Flashed(1); flashed {
+		t.Error("node was flashed")
+	}
+}
+
+func TestNodeFlashModifyPlanDefaultCache(t *testing.T) {
+	ctx := context.Background()
+	r, empty := newTestResource(t, fake.New(fake.WithDefaultCache(client.CacheLocationLocal)))
+
+	plan := tfsdk.Plan{Schema: empty.Schema, Raw: empty.Raw}
+	if diags := plan.Set(ctx, model(1, "image.img", "")); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	resp := resource.ModifyPlanResponse{Plan: plan}
+	r.ModifyPlan(ctx, resource.ModifyPlanRequest{Plan: plan, State: empty}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("ModifyPlan: %v", resp.Diagnostics)
+	}
+
+	var cache types.String
+	resp.Plan.GetAttribute(ctx, path.Root("cache"), &cache)
+	if cache.ValueString() != client.CacheLocationLocal {
+		t.Errorf("planned cache = %s, want %s", cache, client.CacheLocationLocal)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"github.com/davidroman0O/terraform-provider-turingpi/internal/client"
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/diagnostics"
 	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
@@ -21,6 +22,7 @@ import (
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &NodePowerResource{}
 var _ resource.ResourceWithImportState = &NodePowerResource{}
+var _ resource.ResourceWithModifyPlan = &NodePowerResource{}
 
 func NewNodePowerResource() resource.Resource {
 	return &NodePowerResource{}
@@ -28,7 +30,7 @@ func NewNodePowerResource() resource.Resource {
 
 // NodePowerResource defines the resource implementation.
 type NodePowerResource struct {
-	client *client.Client
+	client client.BMC
 }
 
 // NodePowerResourceModel describes the resource data model.
@@ -76,11 +78,11 @@ func (r *NodePowerResource) Configure(ctx context.Context, req resource.Configur
 		return
 	}
 
-	client, ok := req.ProviderData.(*client.Client)
+	client, ok := req.ProviderData.(client.BMC)
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Resource Configure Type",
-			fmt.Sprintf("Expected *client.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected client.BMC, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 		return
 	}
@@ -88,6 +90,11 @@ func (r *NodePowerResource) Configure(ctx context.Context, req resource.Configur
 	r.client = client
 }
 
+// ModifyPlan rejects changes when the provider is read-only.
+func (r *NodePowerResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	resp.Diagnostics.Append(diagnostics.ReadOnlyPlan(r.client, req, "turingpi_node_power")...)
+}
+
 func (r *NodePowerResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
 	var plan NodePowerResourceModel
 
@@ -100,12 +107,13 @@ func (r *NodePowerResource) Create(ctx context.Context, req resource.CreateReque
 	desiredState := plan.PowerOn.ValueBool()
 
 	// Check current state for idempotency
-	status, err := r.client.PowerStatus()
+	status, err := r.client.PowerStatusContext(ctx)
 	if err != nil {
-		resp.Diagnostics.AddError(
+		resp.Diagnostics.Append(diagnostics.ClientError(
 			"Error Reading Power Status",
-			fmt.Sprintf("Could not read power status: %s", err.Error()),
-		)
+			"Could not read power status",
+			err,
+		))
 		return
 	}
 
@@ -114,15 +122,16 @@ func (r *NodePowerResource) Create(ctx context.Context, req resource.CreateReque
 	// Only change if different (idempotent)
 	if currentState != desiredState {
 		if desiredState {
-			err = r.client.PowerOn(node)
+			err = r.client.PowerOnContext(ctx, node)
 		} else {
-			err = r.client.PowerOff(node)
+			err = r.client.PowerOffContext(ctx, node)
 		}
 		if err != nil {
-			resp.Diagnostics.AddError(
+			resp.Diagnostics.Append(diagnostics.ClientError(
 				"Error Setting Power State",
-				fmt.Sprintf("Could not set power state for node %d: %s", node, err.Error()),
-			)
+				fmt.Sprintf("Could not set power state for node %d", node),
+				err,
+			))
 			return
 		}
 	}
@@ -142,12 +151,13 @@ func (r *NodePowerResource) Read(ctx context.Context, req resource.ReadRequest,
 
 	node := int(state.Node.ValueInt64())
 
-	status, err := r.client.PowerStatus()
+	status, err := r.client.PowerStatusContext(ctx)
 	if err != nil {
-		resp.Diagnostics.AddError(
+		resp.Diagnostics.Append(diagnostics.ClientError(
 			"Error Reading Power Status",
-			fmt.Sprintf("Could not read power status: %s", err.Error()),
-		)
+			"Could not read power status",
+			err,
+		))
 		return
 	}
 
@@ -168,12 +178,13 @@ func (r *NodePowerResource) Update(ctx context.Context, req resource.UpdateReque
 	desiredState := plan.PowerOn.ValueBool()
 
 	// Check current state for idempotency
-	status, err := r.client.PowerStatus()
+	status, err := r.client.PowerStatusContext(ctx)
 	if err != nil {
-		resp.Diagnostics.AddError(
+		resp.Diagnostics.Append(diagnostics.ClientError(
 			"Error Reading Power Status",
-			fmt.Sprintf("Could not read power status: %s", err.Error()),
-		)
+			"Could not read power status",
+			err,
+		))
 		return
 	}
 
@@ -182,15 +193,16 @@ func (r *NodePowerResource) Update(ctx context.Context, req resource.UpdateReque
 	// Only change if different
 	if currentState != desiredState {
 		if desiredState {
-			err = r.client.PowerOn(node)
+			err = r.client.PowerOnContext(ctx, node)
 		} else {
-			err = r.client.PowerOff(node)
+			err = r.client.PowerOffContext(ctx, node)
 		}
 		if err != nil {
-			resp.Diagnostics.AddError(
+			resp.Diagnostics.Append(diagnostics.ClientError(
 				"Error Setting Power State",
-				fmt.Sprintf("Could not set power state for node %d: %s", node, err.Error()),
-			)
+				fmt.Sprintf("Could not set power state for node %d", node),
+				err,
+			))
 			return
 		}
 	}
@@ -0,0 +1,147 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package node_power
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/client/fake"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func model(node int64, powerOn bool) *NodePowerResourceModel {
+	return &NodePowerResourceModel{
+		ID:      types.StringUnknown(),
+		Node:    types.Int64Value(node),
+		PowerOn: types.BoolValue(powerOn),
+	}
+}
+
+func TestNodePowerCreate(t *testing.T) {
+	ctx := context.Background()
+	bmc := fake.New()
+	r, empty := fake.ConfigureResource(t, &NodePowerResource{}, bmc)
+
+	plan := tfsdk.Plan{Schema: empty.Schema, Raw: empty.Raw}
+	if diags := plan.Set(ctx, model(2, true)); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	resp := resource.CreateResponse{State: empty}
+	r.Create(ctx, resource.CreateRequest{Plan: plan}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Create: %v", resp.Diagnostics)
+	}
+
+	var state NodePowerResourceModel
+	resp.State.Get(ctx, &state)
+	if state.ID.ValueString() != "node-2-power" {
+		t.Errorf("id = %s, want node-2-power", state.ID)
+	}
+	if !bmc.Power(2) {
+		t.Error("node 2 was not powered on")
+	}
+
+	// Creating again with the node already on must not toggle it
+	r.Create(ctx, resource.CreateRequest{Plan: plan}, &resource.CreateResponse{State: empty})
+	if calls := bmc.Calls("PowerOn"); calls != 1 {
+		t.Errorf("PowerOn called %d times, want 1", calls)
+	}
+}
+
+func TestNodePowerCreateError(t *testing.T) {
+	ctx := context.Background()
+	bmc := fake.New()
+	bmc.FailNext("PowerOn", errors.New("request failed with status 500"))
+	r, empty := fake.ConfigureResource(t, &NodePowerResource{}, bmc)
+
+	plan := tfsdk.Plan{Schema: empty.Schema, Raw: empty.Raw}
+	if diags := plan.Set(ctx, model(1, true)); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	resp := resource.CreateResponse{State: empty}
+	r.Create(ctx, resource.CreateRequest{Plan: plan}, &resp)
+	if !resp.Diagnostics.HasError() {
+		t.Fatal("expected an error diagnostic")
+	}
+	if !resp.State.Raw.IsNull() {
+		t.Error("state was written for a failed create")
+	}
+}
+
+func TestNodePowerReadDetectsDrift(t *testing.T) {
+	ctx := context.Background()
+	bmc := fake.New()
+	r, empty := fake.ConfigureResource(t, &NodePowerResource{}, bmc)
+
+	state := tfsdk.State{Schema: empty.Schema, Raw: empty.Raw}
+	current := model(3, true)
+	current.ID = types.StringValue("node-3-power")
+	if diags := state.Set(ctx, current); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	resp := resource.ReadResponse{State: state}
+	r.Read(ctx, resource.ReadRequest{State: state}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Read: %v", resp.Diagnostics)
+	}
+
+	var got NodePowerResourceModel
+	resp.State.Get(ctx, &got)
+	if got.PowerOn.ValueBool() {
+		t.Error("power_on = true, want false after the node was powered off outside Terraform")
+	}
+}
+
+func TestNodePowerUpdate(t *testing.T) {
+	ctx := context.Background()
+	bmc := fake.New()
+	bmc.SetPower(4, true)
+	r, empty := fake.ConfigureResource(t, &NodePowerResource{}, bmc)
+
+	prior := model(4, true)
+	prior.ID = types.StringValue("node-4-power")
+	state := tfsdk.State{Schema: empty.Schema, Raw: empty.Raw}
+	if diags := state.Set(ctx, prior); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	desired := model(4, false)
+	desired.ID = types.StringValue("node-4-power")
+	plan := tfsdk.Plan{Schema: empty.Schema, Raw: empty.Raw}
+	if diags := plan.Set(ctx, desired); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	resp := resource.UpdateResponse{State: state}
+	r.Update(ctx, resource.UpdateRequest{Plan: plan, State: state}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Update: %v", resp.Diagnostics)
+	}
+	if bmc.Power(4) {
+		t.Error("node 4 is still powered on")
+	}
+}
+
+func TestNodePowerModifyPlanReadOnly(t *testing.T) {
+	ctx := context.Background()
+	r, empty := fake.ConfigureResource(t, &NodePowerResource{}, fake.New(fake.WithReadOnly()))
+
+	plan := tfsdk.Plan{Schema: empty.Schema, Raw: empty.Raw}
+	if diags := plan.Set(ctx, model(1, true)); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	resp := resource.ModifyPlanResponse{Plan: plan}
+	r.ModifyPlan(ctx, resource.ModifyPlanRequest{Plan: plan, State: empty}, &resp)
+	if !resp.Diagnostics.HasError() {
+		t.Error("expected a read-only error for a planned create")
+	}
+}
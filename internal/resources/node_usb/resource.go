@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"github.com/davidroman0O/terraform-provider-turingpi/internal/client"
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/diagnostics"
 	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/path"
@@ -23,6 +24,7 @@ import (
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &NodeUsbResource{}
 var _ resource.ResourceWithImportState = &NodeUsbResource{}
+var _ resource.ResourceWithModifyPlan = &NodeUsbResource{}
 
 func NewNodeUsbResource() resource.Resource {
 	return &NodeUsbResource{}
@@ -30,7 +32,7 @@ func NewNodeUsbResource() resource.Resource {
 
 // NodeUsbResource defines the resource implementation.
 type NodeUsbResource struct {
-	client *client.Client
+	client client.BMC
 }
 
 // NodeUsbResourceModel describes the resource data model.
@@ -89,11 +91,11 @@ func (r *NodeUsbResource) Configure(ctx context.Context, req resource.ConfigureR
 		return
 	}
 
-	client, ok := req.ProviderData.(*client.Client)
+	client, ok := req.ProviderData.(client.BMC)
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Resource Configure Type",
-			fmt.Sprintf("Expected *client.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected client.BMC, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 		return
 	}
@@ -101,6 +103,11 @@ func (r *NodeUsbResource) Configure(ctx context.Context, req resource.ConfigureR
 	r.client = client
 }
 
+// ModifyPlan rejects changes when the provider is read-only.
+func (r *NodeUsbResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	resp.Diagnostics.Append(diagnostics.ReadOnlyPlan(r.client, req, "turingpi_node_usb")...)
+}
+
 func (r *NodeUsbResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
 	var plan NodeUsbResourceModel
 
@@ -113,12 +120,13 @@ func (r *NodeUsbResource) Create(ctx context.Context, req resource.CreateRequest
 	mode := plan.Mode.ValueString()
 	bmc := plan.BMC.ValueBool()
 
-	err := r.setUsbMode(node, mode, bmc)
+	err := r.setUsbMode(ctx, node, mode, bmc)
 	if err != nil {
-		resp.Diagnostics.AddError(
+		resp.Diagnostics.Append(diagnostics.ClientError(
 			"Error Setting USB Mode",
-			fmt.Sprintf("Could not set USB mode for node %d: %s", node, err.Error()),
-		)
+			fmt.Sprintf("Could not set USB mode for node %d", node),
+			err,
+		))
 		return
 	}
 
@@ -135,12 +143,13 @@ func (r *NodeUsbResource) Read(ctx context.Context, req resource.ReadRequest, re
 		return
 	}
 
-	status, err := r.client.UsbGetStatus()
+	status, err := r.client.UsbGetStatusContext(ctx)
 	if err != nil {
-		resp.Diagnostics.AddError(
+		resp.Diagnostics.Append(diagnostics.ClientError(
 			"Error Reading USB Status",
-			fmt.Sprintf("Could not read USB status: %s", err.Error()),
-		)
+			"Could not read USB status",
+			err,
+		))
 		return
 	}
 
@@ -166,12 +175,13 @@ func (r *NodeUsbResource) Update(ctx context.Context, req resource.UpdateRequest
 	mode := plan.Mode.ValueString()
 	bmc := plan.BMC.ValueBool()
 
-	err := r.setUsbMode(node, mode, bmc)
+	err := r.setUsbMode(ctx, node, mode, bmc)
 	if err != nil {
-		resp.Diagnostics.AddError(
+		resp.Diagnostics.Append(diagnostics.ClientError(
 			"Error Setting USB Mode",
-			fmt.Sprintf("Could not set USB mode for node %d: %s", node, err.Error()),
-		)
+			fmt.Sprintf("Could not set USB mode for node %d", node),
+			err,
+		))
 		return
 	}
 
@@ -187,14 +197,14 @@ func (r *NodeUsbResource) ImportState(ctx context.Context, req resource.ImportSt
 }
 
 // setUsbMode sets the USB mode for the specified node.
-func (r *NodeUsbResource) setUsbMode(node int, mode string, bmc bool) error {
+func (r *NodeUsbResource) setUsbMode(ctx context.Context, node int, mode string, bmc bool) error {
 	switch mode {
 	case "host":
-		return r.client.UsbSetHost(node, bmc)
+		return r.client.UsbSetHostContext(ctx, node, bmc)
 	case "device":
-		return r.client.UsbSetDevice(node, bmc)
+		return r.client.UsbSetDeviceContext(ctx, node, bmc)
 	case "flash":
-		return r.client.UsbSetFlash(node, bmc)
+		return r.client.UsbSetFlashContext(ctx, node, bmc)
 	default:
 		return fmt.Errorf("unknown USB mode: %s", mode)
 	}
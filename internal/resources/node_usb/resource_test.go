@@ -0,0 +1,106 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+package node_usb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/client/fake"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func model(node int64, mode string, bmc bool) *NodeUsbResourceModel {
+	return &NodeUsbResourceModel{
+		ID:   types.StringUnknown(),
+		Node: types.Int64Value(node),
+		Mode: types.StringValue(mode),
+		BMC:  types.BoolValue(bmc),
+	}
+}
+
+func TestNodeUsbCreateAndRead(t *testing.T) {
+	ctx := context.Background()
+	bmc := fake.New()
+	r, empty := fake.ConfigureResource(t, &NodeUsbResource{}, bmc)
+
+	plan := tfsdk.Plan{Schema: empty.Schema, Raw: empty.Raw}
+	if diags := plan.Set(ctx, model(3, "host", true)); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	createResp := resource.CreateResponse{State: empty}
+	r.Create(ctx, resource.CreateRequest{Plan: plan}, &createResp)
+	if createResp.Diagnostics.HasError() {
+		t.Fatalf("Create: %v", createResp.Diagnostics)
+	}
+	if usb := bmc.USB(); usb.Node != "Node 3" || usb.Mode != "Host" || usb.Route != "BMC" {
+		t.Errorf("BMC USB status = %+v, want node 3 in host mode via BMC", usb)
+	}
+
+	readResp := resource.ReadResponse{State: createResp.State}
+	r.Read(ctx, resource.ReadRequest{State: createResp.State}, &readResp)
+	if readResp.Diagnostics.HasError() {
+		t.Fatalf("Read: %v", readResp.Diagnostics)
+	}
+
+	var state NodeUsbResourceModel
+	readResp.State.Get(ctx, &state)
+	if state.ID.ValueString() != "node-3-usb" || state.Mode.ValueString() != "host" || !state.BMC.ValueBool() {
+		t.Errorf("state = %+v", state)
+	}
+}
+
+func TestNodeUsbUpdate(t *testing.T) {
+	ctx := context.Background()
+	bmc := fake.New()
+	r, empty := fake.ConfigureResource(t, &NodeUsbResource{}, bmc)
+
+	prior := model(1, "device", false)
+	prior.ID = types.StringValue("node-1-usb")
+	state := tfsdk.State{Schema: empty.Schema, Raw: empty.Raw}
+	if diags := state.Set(ctx, prior); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	desired := model(1, "flash", false)
+	desired.ID = types.StringValue("node-1-usb")
+	plan := tfsdk.Plan{Schema: empty.Schema, Raw: empty.Raw}
+	if diags := plan.Set(ctx, desired); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	resp := resource.UpdateResponse{State: state}
+	r.Update(ctx, resource.UpdateRequest{Plan: plan, State: state}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Update: %v", resp.Diagnostics)
+	}
+	if usb := bmc.USB(); usb.Mode != "Flash" || usb.Route != "USB-A" {
+		t.Errorf("BMC USB status = %+v, want flash mode via USB-A", usb)
+	}
+}
+
+func TestNodeUsbUpdateError(t *testing.T) {
+	ctx := context.Background()
+	bmc := fake.New()
+	bmc.FailNext("UsbSetHost", errors.New("USB configuration failed"))
+	r, empty := fake.ConfigureResource(t, &NodeUsbResource{}, bmc)
+
+	plan := tfsdk.Plan{Schema: empty.Schema, Raw: empty.Raw}
+	if diags := plan.Set(ctx, model(2, "host", false)); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	resp := resource.UpdateResponse{State: empty}
+	r.Update(ctx, resource.UpdateRequest{Plan: plan, State: empty}, &resp)
+	if !resp.Diagnostics.HasError() {
+		t.Error("expected an error diagnostic")
+	}
+	if usb := bmc.USB(); usb.Mode != "Device" {
+		t.Errorf("USB mode changed to %s despite the failure", usb.Mode)
+	}
+}
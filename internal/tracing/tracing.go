@@ -0,0 +1,102 @@
+// Copyright (c) David Roman
+// SPDX-License-Identifier: MPL-2.0
+
+// Package tracing sets up optional OpenTelemetry trace export for the
+// provider process. It is configured entirely through the standard OTEL_*
+// environment variables, so the same settings work for every OTLP-capable
+// tool in a pipeline.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// ServiceName is reported when OTEL_SERVICE_NAME is not set.
+const ServiceName = "terraform-provider-turingpi"
+
+// Enabled reports whether the environment asks for trace export: an OTLP
+// endpoint is configured or OTEL_TRACES_EXPORTER is "otlp", and neither
+// OTEL_SDK_DISABLED nor OTEL_TRACES_EXPORTER=none turns it off.
+func Enabled() bool {
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		return false
+	}
+	switch strings.ToLower(os.Getenv("OTEL_TRACES_EXPORTER")) {
+	case "none":
+		return false
+	case "otlp":
+		return true
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" ||
+		os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// Setup installs a global tracer provider exporting over OTLP when Enabled,
+// and otherwise leaves the no-op default in place. The returned function
+// flushes buffered spans and must be called before the process exits.
+//
+// The exporter reads the endpoint, headers, TLS and timeout settings from
+// OTEL_EXPORTER_OTLP_*; OTEL_EXPORTER_OTLP_PROTOCOL selects "http/protobuf"
+// (the default) or "grpc".
+func Setup(ctx context.Context, version string) (func(context.Context) error, error) {
+	if !Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The environment detector applies OTEL_SERVICE_NAME and
+	// OTEL_RESOURCE_ATTRIBUTES on top of these defaults
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(
+			attribute.String("service.name", ServiceName),
+			attribute.String("service.version", version),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+	res, err = resource.Merge(res, resource.Environment())
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// newExporter creates the OTLP exporter for the configured protocol.
+func newExporter(ctx context.Context) (*otlptrace.Exporter, error) {
+	protocol := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL")
+	if protocol == "" {
+		protocol = os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+	}
+
+	switch protocol {
+	case "", "http/protobuf":
+		return otlptracehttp.New(ctx)
+	case "grpc":
+		return otlptracegrpc.New(ctx)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q (must be \"http/protobuf\" or \"grpc\")", protocol)
+	}
+}
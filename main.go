@@ -7,8 +7,10 @@ import (
 	"context"
 	"flag"
 	"log"
+	"time"
 
 	"github.com/davidroman0O/terraform-provider-turingpi/internal/provider"
+	"github.com/davidroman0O/terraform-provider-turingpi/internal/tracing"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 )
 
@@ -29,7 +31,25 @@ func main() {
 		Debug:   debug,
 	}
 
-	err := providerserver.Serve(context.Background(), provider.New(version), opts)
+	ctx := context.Background()
+	shutdownTracing, err := tracing.Setup(ctx, version)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	err = providerserver.Serve(ctx, provider.New(version), opts)
+
+	// Close BMC connections and flush spans still buffered when Terraform
+	// stops the provider
+	flushCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if closeErr := provider.Shutdown(flushCtx); closeErr != nil {
+		log.Printf("failed to close BMC connections: %s", closeErr)
+	}
+	if shutdownErr := shutdownTracing(flushCtx); shutdownErr != nil {
+		log.Printf("failed to flush traces: %s", shutdownErr)
+	}
+
 	if err != nil {
 		log.Fatal(err.Error())
 	}